@@ -0,0 +1,474 @@
+package bencode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+)
+
+// gotEOF is what a SyntaxError reports as "Got" when the input ends too early.
+const gotEOF = "EOF"
+
+// A SyntaxError describes malformed or truncated bencode.
+type SyntaxError struct {
+	// Offset is the position in the input where the problem was found.
+	Offset int64
+	// Expected describes what the decoder was looking for.
+	Expected string
+	// Got is what was found instead, it's "EOF" when the input ended too early.
+	Got string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("Expected %s at position '%d' got %s instead", e.Expected, e.Offset, e.Got)
+}
+
+// Unwrap lets callers check errors.Is(err, io.ErrUnexpectedEOF)
+// to tell a truncated input apart from a malformed one.
+func (e *SyntaxError) Unwrap() error {
+	if e.Got == gotEOF {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+// Unmarshal decodes the bencoded data and stores the result in the value pointed to by v.
+//
+// Dicts are decoded into structs (see Marshal for how keys are matched to fields),
+// maps with string keys or map[string]any.
+// Lists are decoded into slices, arrays or []any.
+// Strings are decoded into string, []byte or a [N]byte array of the exact same length.
+// Ints are decoded into any int/uint kind or big.Int, an *IntOverflowError is returned when the value
+// does not fit. Any non zero int decodes to true into a bool.
+// Decoding into an empty interface produces map[string]any, []any, string and int64 values.
+// Types implementing BencodeUnmarshaler decode themselves.
+//
+// The input is first validated with Parse and then decoded from the resulting Value.
+//
+// Unmarshal is lenient: it accepts unsorted or duplicated dict keys (the last one wins)
+// and ints or string lengths with leading zeros since real world torrents do contain them.
+// Use UnmarshalStrict to only accept the canonical form described in BEP 3.
+func Unmarshal(data []byte, v any) error {
+	d := decodeState{data: data, limits: DefaultLimits}
+	return d.unmarshal(v)
+}
+
+// UnmarshalStrict is like Unmarshal but fails with a *SyntaxError on any non canonical encoding.
+func UnmarshalStrict(data []byte, v any) error {
+	d := decodeState{data: data, strict: true, limits: DefaultLimits}
+	return d.unmarshal(v)
+}
+
+type decodeState struct {
+	data   []byte
+	pos    int
+	strict bool
+
+	// base is added to positions reported in errors,
+	// it's used when "data" is only a part of a bigger stream.
+	base int64
+
+	limits Limits
+	depth  int
+	alloc  int64
+
+	// ordered makes empty interfaces hold *OrderedDict instead of map[string]any.
+	ordered bool
+	// bigInts makes empty interfaces hold *big.Int instead of int64.
+	bigInts bool
+}
+
+// parse validates the whole input as a single value.
+func (d *decodeState) parse() (Value, error) {
+	val, err := d.consumeValue()
+	if err != nil {
+		return Value{}, err
+	}
+
+	if d.pos != len(d.data) {
+		return Value{}, d.syntaxError("end of input")
+	}
+
+	return val, nil
+}
+
+func (d *decodeState) unmarshal(v any) error {
+	val, err := d.parse()
+	if err != nil {
+		return err
+	}
+
+	return d.unmarshalInto(val, v)
+}
+
+func (d *decodeState) unmarshalInto(val Value, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New(fmt.Sprintf("Unmarshal expects a non nil pointer got %T instead", v))
+	}
+
+	return d.unmarshalValue(val, rv.Elem())
+}
+
+// syntaxError reports that "expected" was not found at the current position.
+func (d *decodeState) syntaxError(expected string) error {
+	return d.syntaxErrorAt(d.pos, expected)
+}
+
+func (d *decodeState) syntaxErrorAt(pos int, expected string) error {
+	got := gotEOF
+	if pos < len(d.data) {
+		got = strconv.QuoteRune(rune(d.data[pos]))
+	}
+
+	return &SyntaxError{Offset: d.base + int64(pos), Expected: expected, Got: got}
+}
+
+/*
+* The "unmarshalXXX" functions work on already validated values,
+* the only errors they can return are type mismatches and overflows.
+ */
+
+func (d *decodeState) unmarshalValue(val Value, rv reflect.Value) error {
+	if rv.Type() == rawMessageType {
+		raw := make(RawMessage, len(val.Raw()))
+		copy(raw, val.Raw())
+		rv.SetBytes(raw)
+		return nil
+	}
+
+	if u, ok := unmarshaler(rv); ok {
+		return u.UnmarshalBencode(val.Raw())
+	}
+
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return d.unmarshalValue(val, rv.Elem())
+	}
+
+	if rv.Kind() == reflect.Interface && rv.NumMethod() == 0 {
+		v, err := d.materialize(val)
+		if err != nil {
+			return err
+		}
+		rv.Set(reflect.ValueOf(v))
+		return nil
+	}
+
+	switch val.Kind() {
+	case Int:
+		return d.unmarshalInt(val, rv)
+	case List:
+		return d.unmarshalList(val, rv)
+	case Dict:
+		return d.unmarshalDict(val, rv)
+	default:
+		return d.unmarshalString(val, rv)
+	}
+}
+
+func (d *decodeState) unmarshalString(val Value, rv reflect.Value) error {
+	str := val.Bytes()
+
+	switch {
+	case rv.Kind() == reflect.String:
+		rv.SetString(string(str))
+
+	case rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8:
+		rv.SetBytes(bytes.Clone(str))
+
+	case rv.Kind() == reflect.Array && rv.Type().Elem().Kind() == reflect.Uint8:
+		if len(str) != rv.Len() {
+			return errors.New(fmt.Sprintf("Expected a string of length %d at position '%d' got %d instead", rv.Len(), d.offset(val), len(str)))
+		}
+		reflect.Copy(rv, reflect.ValueOf(str))
+
+	default:
+		return d.typeMismatch(val, rv.Type())
+	}
+
+	return nil
+}
+
+func (d *decodeState) unmarshalList(val Value, rv reflect.Value) error {
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return d.typeMismatch(val, rv.Type())
+	}
+
+	if rv.Kind() == reflect.Slice {
+		rv.Set(reflect.MakeSlice(rv.Type(), 0, 0))
+	}
+
+	i := 0
+	var err error
+	val.Range(func(_ []byte, elem Value) bool {
+		if rv.Kind() == reflect.Slice {
+			rv.Set(reflect.Append(rv, reflect.Zero(rv.Type().Elem())))
+		} else if i >= rv.Len() {
+			err = errors.New(fmt.Sprintf("List at position '%d' has more elements than %s", d.offset(val), rv.Type()))
+			return false
+		}
+
+		err = d.unmarshalValue(elem, rv.Index(i))
+		i++
+		return err == nil
+	})
+
+	return err
+}
+
+func (d *decodeState) unmarshalDict(val Value, rv reflect.Value) error {
+	var fields []field
+	switch {
+	case rv.Kind() == reflect.Struct:
+		fields = cachedFields(rv.Type())
+
+	case rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String:
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMap(rv.Type()))
+		}
+
+	default:
+		return d.typeMismatch(val, rv.Type())
+	}
+
+	var err error
+	val.Range(func(key []byte, elem Value) bool {
+		if rv.Kind() == reflect.Map {
+			v := reflect.New(rv.Type().Elem()).Elem()
+			if err = d.unmarshalValue(elem, v); err != nil {
+				return false
+			}
+			rv.SetMapIndex(reflect.ValueOf(string(key)).Convert(rv.Type().Key()), v)
+			return true
+		}
+
+		if f := lookupField(fields, string(key)); f != nil {
+			err = d.unmarshalValue(elem, rv.Field(f.index))
+		} else if f := extraField(fields); f != nil {
+			err = d.unmarshalExtra(key, elem, rv.Field(f.index))
+		}
+		// unknown keys are skipped when there is no extra field
+		return err == nil
+	})
+
+	return err
+}
+
+// unmarshalExtra stores a key that has no struct field into the `bencode:",extra"` map.
+func (d *decodeState) unmarshalExtra(key []byte, elem Value, extra reflect.Value) error {
+	if extra.IsNil() {
+		extra.Set(reflect.MakeMap(extra.Type()))
+	}
+
+	v := reflect.New(extra.Type().Elem()).Elem()
+	if err := d.unmarshalValue(elem, v); err != nil {
+		return err
+	}
+	extra.SetMapIndex(reflect.ValueOf(string(key)).Convert(extra.Type().Key()), v)
+	return nil
+}
+
+func (d *decodeState) offset(val Value) int64 {
+	return d.base + int64(val.start)
+}
+
+func (d *decodeState) typeMismatch(val Value, typ reflect.Type) error {
+	return errors.New(fmt.Sprintf("Cannot decode bencode %s at position '%d' into Go value of type %s", val.Kind(), d.offset(val), typ))
+}
+
+// checkKeyOrder enforces (in strict mode) that the i-th key of a dict
+// is strictly greater than the previous one, that is sorted with no duplicates.
+func (d *decodeState) checkKeyOrder(i int, prevKey, key Value) error {
+	if !d.strict || i == 0 || bytes.Compare(prevKey.Bytes(), key.Bytes()) < 0 {
+		return nil
+	}
+
+	return &SyntaxError{
+		Offset:   d.offset(key),
+		Expected: fmt.Sprintf("a key sorted after %q", prevKey.Bytes()),
+		Got:      strconv.Quote(string(key.Bytes())),
+	}
+}
+
+/*
+* PLEASE NOTE ALL "consumeXXX" FUNC WILL POSITION "pos" AFTER THE PARSED VALUE
+* They only validate the input and return a view of the value, nothing is copied.
+ */
+
+func (d *decodeState) consumeValue() (Value, error) {
+	if d.pos >= len(d.data) {
+		return Value{}, d.syntaxError("a value")
+	}
+
+	switch c := d.data[d.pos]; {
+	case c == 'd':
+		return d.consumeDict()
+	case c == 'l':
+		return d.consumeList()
+	case c == 'i':
+		return d.consumeInt()
+	case isDigit(c):
+		return d.consumeString()
+	default:
+		return Value{}, d.syntaxError("a value")
+	}
+}
+
+func (d *decodeState) consumeDict() (Value, error) {
+	if d.pos >= len(d.data) || d.data[d.pos] != 'd' {
+		return Value{}, d.syntaxError("'d'")
+	}
+
+	if err := d.enter(); err != nil {
+		return Value{}, err
+	}
+	defer d.leave()
+
+	start := d.pos
+	d.pos++ // skip the d
+	var prevKey Value
+	for i := 0; d.pos < len(d.data) && d.data[d.pos] != 'e'; i++ {
+		if err := d.addElement(i + 1); err != nil {
+			return Value{}, err
+		}
+
+		key, err := d.consumeString()
+		if err != nil {
+			return Value{}, err
+		}
+
+		if err := d.checkKeyOrder(i, prevKey, key); err != nil {
+			return Value{}, err
+		}
+		prevKey = key
+
+		if _, err := d.consumeValue(); err != nil {
+			return Value{}, err
+		}
+	}
+
+	if d.pos >= len(d.data) {
+		return Value{}, d.syntaxError("'e'")
+	}
+
+	d.pos++ // skip the 'e'
+	return Value{buf: d.data, start: start, end: d.pos}, nil
+}
+
+func (d *decodeState) consumeList() (Value, error) {
+	if d.pos >= len(d.data) || d.data[d.pos] != 'l' {
+		return Value{}, d.syntaxError("'l'")
+	}
+
+	if err := d.enter(); err != nil {
+		return Value{}, err
+	}
+	defer d.leave()
+
+	start := d.pos
+	d.pos++ // skip the 'l'
+	for i := 1; d.pos < len(d.data) && d.data[d.pos] != 'e'; i++ {
+		if err := d.addElement(i); err != nil {
+			return Value{}, err
+		}
+
+		if _, err := d.consumeValue(); err != nil {
+			return Value{}, err
+		}
+	}
+
+	if d.pos >= len(d.data) {
+		return Value{}, d.syntaxError("'e'")
+	}
+
+	d.pos++ // skip the 'e'
+	return Value{buf: d.data, start: start, end: d.pos}, nil
+}
+
+func (d *decodeState) consumeString() (Value, error) {
+	start := d.pos
+	i := start
+	for i < len(d.data) && isDigit(d.data[i]) {
+		i++
+	}
+
+	if i == start {
+		return Value{}, d.syntaxErrorAt(i, "a string length")
+	}
+	if i >= len(d.data) || d.data[i] != ':' {
+		return Value{}, d.syntaxErrorAt(i, "':'")
+	}
+	if d.strict && d.data[start] == '0' && i-start > 1 {
+		return Value{}, d.syntaxErrorAt(start, "a string length without leading zeros")
+	}
+
+	strLen, err := strconv.Atoi(string(d.data[start:i]))
+	if err != nil {
+		return Value{}, errors.New(fmt.Sprintf("Cannot convert string length at %d got '%s' instead", d.base+int64(start), d.data[start:i]))
+	}
+
+	if err := d.addString(strLen); err != nil {
+		return Value{}, err
+	}
+
+	i++ // skip the ':'
+	if strLen > len(d.data)-i {
+		return Value{}, &SyntaxError{
+			Offset:   d.base + int64(len(d.data)),
+			Expected: fmt.Sprintf("%d more bytes of the string starting at %d", strLen-(len(d.data)-i), d.base+int64(start)),
+			Got:      gotEOF,
+		}
+	}
+
+	d.pos = i + strLen
+	return Value{buf: d.data, start: start, end: d.pos}, nil
+}
+
+func (d *decodeState) consumeInt() (Value, error) {
+	if d.pos >= len(d.data) || d.data[d.pos] != 'i' {
+		return Value{}, d.syntaxError("'i'")
+	}
+
+	start := d.pos + 1
+	i := start
+	if i < len(d.data) && d.data[i] == '-' {
+		i++
+	}
+
+	digitsStart := i
+	for i < len(d.data) && isDigit(d.data[i]) {
+		i++
+	}
+
+	if i == digitsStart {
+		return Value{}, d.syntaxErrorAt(i, "a digit")
+	}
+	if i >= len(d.data) || d.data[i] != 'e' {
+		return Value{}, d.syntaxErrorAt(i, "'e'")
+	}
+
+	if d.strict && d.data[digitsStart] == '0' {
+		if digitsStart != start {
+			return Value{}, d.syntaxErrorAt(digitsStart, "a non zero int after '-'")
+		}
+		if i-digitsStart > 1 {
+			return Value{}, d.syntaxErrorAt(digitsStart, "an int without leading zeros")
+		}
+	}
+
+	val := Value{buf: d.data, start: d.pos, end: i + 1}
+	d.pos = i + 1
+	return val, nil
+}
+
+/* END OF consumeXXX functions */
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
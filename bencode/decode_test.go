@@ -0,0 +1,636 @@
+package bencode
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConsumeDict(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    map[string]any
+		expectError bool
+	}{
+		// should work as expected for empty dict
+		{
+			input:       "de",
+			expected:    map[string]any{},
+			expectError: false,
+		},
+		// should work as expected for single item dict
+		{
+			input:       "d1:ki5ee",
+			expected:    map[string]any{"k": int64(5)},
+			expectError: false,
+		},
+		// should work as expected for list of diff items
+		{
+			input:       "d1:ki5e1:s1:se",
+			expected:    map[string]any{"k": int64(5), "s": "s"},
+			expectError: false,
+		},
+		// should work as expected for nested dicts
+		{
+			input:       "d1:dd1:s1:see",
+			expected:    map[string]any{"d": map[string]any{"s": "s"}},
+			expectError: false,
+		},
+		// should return an error if it's not a dict start
+		{
+			input:       "ve",
+			expected:    nil,
+			expectError: true,
+		},
+		// should return an error if dict does not end properly
+		{
+			input:       "d1:h1:hE",
+			expected:    nil,
+			expectError: true,
+		},
+		// should return an error if key is not string
+		{
+			input:       "di5ei5ee",
+			expected:    nil,
+			expectError: true,
+		},
+		{
+			input:       "dl1:hei5ee",
+			expected:    nil,
+			expectError: true,
+		},
+		{
+			input:       "ddei5ee",
+			expected:    nil,
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		d := decodeState{data: []byte(test.input)}
+		val, err := d.consumeDict()
+		res := materialize[map[string]any](t, val, err)
+
+		if test.expectError && err == nil {
+			t.Errorf("expected an error but got '%s' as input %d", test.input, d.pos)
+		}
+
+		if !test.expectError {
+			if err != nil {
+				t.Errorf("was not expecting an error got '%s' instead", err)
+			}
+
+			if !reflect.DeepEqual(res, test.expected) {
+				t.Errorf("inputted '%s', expected ( %+v ) got ( %+v )", test.input, test.expected, res)
+			}
+		}
+
+	}
+}
+
+func TestConsumeList(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    []any
+		expectError bool
+	}{
+		// should work as expected for empty list
+		{
+			input:       "le",
+			expected:    []any{},
+			expectError: false,
+		},
+		// should work as expected for single item list
+		{
+			input:       "li5ee",
+			expected:    []any{int64(5)},
+			expectError: false,
+		},
+		// should work as expected for list of diff items
+		{
+			input:       "li5ei32e1:he",
+			expected:    []any{int64(5), int64(32), "h"},
+			expectError: false,
+		},
+		// should work as expected for nested lists
+		{
+			input:       "li3el1:hee",
+			expected:    []any{int64(3), []any{"h"}},
+			expectError: false,
+		},
+		{
+			input:       "lli5ee1:se",
+			expected:    []any{[]any{int64(5)}, "s"},
+			expectError: false,
+		},
+		{
+			input:       "lded1:h1:hee",
+			expected:    []any{map[string]any{}, map[string]any{"h": "h"}},
+			expectError: false,
+		},
+		// should return an error if an item in the list is wrong
+		{
+			input:       "l1:hhhe",
+			expected:    nil,
+			expectError: true,
+		},
+		{
+			input:       "l1:hhhe",
+			expected:    nil,
+			expectError: true,
+		},
+		{
+			input:       "l",
+			expected:    nil,
+			expectError: true,
+		},
+		{
+			input:       "e",
+			expected:    nil,
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		d := decodeState{data: []byte(test.input)}
+		val, err := d.consumeList()
+		res := materialize[[]any](t, val, err)
+
+		if test.expectError && err == nil {
+			t.Errorf("expected an error but got '%s' as input %d", test.input, d.pos)
+		}
+
+		if !test.expectError && err != nil {
+			t.Errorf("was not expecting an error got '%s' instead", err)
+		}
+
+		if !reflect.DeepEqual(res, test.expected) {
+			t.Errorf("inputted '%s', expected ( %+v ) got ( %+v )", test.input, test.expected, res)
+		}
+	}
+}
+
+func TestConsumeInt(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    int64
+		expectError bool
+	}{
+		// should work as expected for single digits number
+		{
+			input:       "i0e",
+			expected:    0,
+			expectError: false,
+		},
+		// should work as expected for multiple digits number
+		{
+			input:       "i555555e",
+			expected:    555555,
+			expectError: false,
+		},
+		{
+			input:       "i-12e",
+			expected:    -12,
+			expectError: false,
+		},
+		// should return error if it's not int
+		{
+			input:       "istringe",
+			expectError: true,
+		},
+		// should return error if it's an empty int
+		{
+			input:       "ie",
+			expectError: true,
+		},
+		// should return error if it's invalid int
+		{
+			input:       "5",
+			expectError: true,
+		},
+		{
+			input:       "i",
+			expectError: true,
+		},
+		{
+			input:       "e",
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		d := decodeState{data: []byte(test.input)}
+		val, err := d.consumeInt()
+		res := materialize[int64](t, val, err)
+
+		if test.expectError && err == nil {
+			t.Errorf("expected an error but got '%s' as input", test.input)
+		}
+
+		if !test.expectError && err != nil {
+			t.Errorf("was not expecting an error got '%s' instead", err)
+		}
+
+		if res != test.expected {
+			t.Errorf("inputted '%s', expected '%d' got '%d'", test.input, test.expected, res)
+		}
+	}
+}
+
+func TestConsumeString(t *testing.T) {
+	tests := []struct {
+		input, expected string
+		expectError     bool
+	}{
+		// should work as expected
+		{
+			input:       "1:h",
+			expected:    "h",
+			expectError: false,
+		},
+		// should parse only specified str len
+		{
+			input:       "1:hh",
+			expected:    "h",
+			expectError: false,
+		},
+		// should return an error if len is neg
+		{
+			input:       "-1:hh",
+			expectError: true,
+		},
+		// should work as expected for empty strings
+		{
+			input:       "0:",
+			expected:    "",
+			expectError: false,
+		},
+		{
+			input:       "0:tt",
+			expected:    "",
+			expectError: false,
+		},
+		// should return an error if len is greater than the input
+		{
+			input:       "5:tt",
+			expectError: true,
+		},
+		{
+			input:       "99999999999999999999:tt",
+			expectError: true,
+		},
+		// should return an error if str is invalid
+		{
+			input:       "0",
+			expectError: true,
+		},
+		{
+			input:       ":ttt",
+			expectError: true,
+		},
+		{
+			input:       "1f",
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		d := decodeState{data: []byte(test.input)}
+		val, err := d.consumeString()
+		res := materialize[string](t, val, err)
+
+		if test.expectError && err == nil {
+			t.Errorf("expected an error but got '%s' as input", test.input)
+		}
+
+		if !test.expectError && err != nil {
+			t.Errorf("was not expecting an error got '%s' instead", err)
+		}
+
+		if res != test.expected {
+			t.Errorf("inputted '%s', expected '%s' got '%s'", test.input, test.expected, res)
+		}
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	input := "d8:announce8:http://t13:announce-listll8:http://tee13:creation datei1724947415e" +
+		"5:extrad4:porti6881ee4:infod5:filesld6:lengthi6203355136e4:pathl1:a1:beee" +
+		"4:name3:dir12:piece lengthi16e6:pieces3:abc7:privatei1e7:unknownli1eeee"
+
+	var res testTorrent
+	if err := Unmarshal([]byte(input), &res); err != nil {
+		t.Fatalf("expected no error got %s instead", err)
+	}
+
+	date := int64(1724947415)
+	expected := testTorrent{
+		Announce:     "http://t",
+		AnnounceList: [][]string{{"http://t"}},
+		CreationDate: &date,
+		Info: &testInfo{
+			Name:        "dir",
+			PieceLength: 16,
+			Pieces:      []byte("abc"),
+			Private:     true,
+			Files:       []testFile{{Length: 6203355136, Path: []string{"a", "b"}}},
+		},
+		Extra: map[string]uint16{"port": 6881},
+	}
+
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("input = %s expected %+v got %+v", input, expected, res)
+	}
+
+	// should round trip back to the exact same bytes minus the unknown key
+	b, err := Marshal(res)
+	if err != nil {
+		t.Fatalf("expected no error got %s instead", err)
+	}
+	if string(b) != strings.Replace(input, "7:unknownli1ee", "", 1) {
+		t.Errorf("expected %s to round trip got %s instead", input, b)
+	}
+}
+
+func TestUnmarshalTypes(t *testing.T) {
+	var (
+		i     int
+		u8    uint8
+		s     string
+		b     []byte
+		arr   [2]byte
+		list  []int
+		m     map[string]string
+		p     *int
+		a     any
+		flag  bool
+		field struct{ Name string }
+		other struct{ Name string }
+	)
+
+	tests := []struct {
+		input    string
+		target   any
+		expected any
+	}{
+		{input: "i-42e", target: &i, expected: -42},
+		{input: "i255e", target: &u8, expected: uint8(255)},
+		{input: "2:hi", target: &s, expected: "hi"},
+		{input: "2:hi", target: &b, expected: []byte("hi")},
+		{input: "2:hi", target: &arr, expected: [2]byte{'h', 'i'}},
+		{input: "li1ei2ee", target: &list, expected: []int{1, 2}},
+		{input: "d1:a1:be", target: &m, expected: map[string]string{"a": "b"}},
+		{input: "i7e", target: &p, expected: func() *int { n := 7; return &n }()},
+		{input: "ld1:ai1eee", target: &a, expected: []any{map[string]any{"a": int64(1)}}},
+		{input: "i1e", target: &flag, expected: true},
+		// untagged fields use their Go name as key
+		{input: "d4:Name1:ne", target: &field, expected: struct{ Name string }{Name: "n"}},
+		// keys are matched exactly
+		{input: "d4:name1:ne", target: &other, expected: struct{ Name string }{}},
+	}
+
+	for _, test := range tests {
+		if err := Unmarshal([]byte(test.input), test.target); err != nil {
+			t.Fatalf("expected no error for input %s got %s instead", test.input, err)
+		}
+
+		res := reflect.ValueOf(test.target).Elem().Interface()
+		if !reflect.DeepEqual(res, test.expected) {
+			t.Errorf("input = %s expected %+v got %+v", test.input, test.expected, res)
+		}
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	var (
+		u8   uint8
+		s    string
+		arr  [2]byte
+		list []int
+	)
+
+	tests := []struct {
+		input  string
+		target any
+	}{
+		// should return an error if the int overflows
+		{input: "i256e", target: &u8},
+		{input: "i-1e", target: &u8},
+		// should return an error on type mismatch
+		{input: "i1e", target: &s},
+		{input: "li1ee", target: &s},
+		{input: "3:abc", target: &arr},
+		{input: "l1:ae", target: &list},
+		// should return an error if there is data after the value
+		{input: "1:ax", target: &s},
+		// should return an error if target is not a pointer
+		{input: "1:a", target: s},
+		{input: "1:a", target: nil},
+	}
+
+	for _, test := range tests {
+		if err := Unmarshal([]byte(test.input), test.target); err == nil {
+			t.Errorf("expected an error for input %s", test.input)
+		}
+	}
+}
+
+func TestUnmarshalRawMessage(t *testing.T) {
+	input := "d4:infod4:name1:n6:lengthi1ee4:spam4:eggse"
+
+	var res struct {
+		Info RawMessage `bencode:"info"`
+		Spam string     `bencode:"spam"`
+	}
+	if err := Unmarshal([]byte(input), &res); err != nil {
+		t.Fatalf("expected no error got %s instead", err)
+	}
+
+	// keys are purposely unsorted, the raw message should keep them as is
+	if string(res.Info) != "d4:name1:n6:lengthi1ee" {
+		t.Errorf("expected raw info to be kept as is got %s instead", res.Info)
+	}
+
+	b, err := Marshal(res)
+	if err != nil {
+		t.Fatalf("expected no error got %s instead", err)
+	}
+	if string(b) != input {
+		t.Errorf("expected %s to round trip got %s instead", input, b)
+	}
+
+	if _, err := Marshal(RawMessage("i1ei2e")); err == nil {
+		t.Errorf("expected an error when RawMessage holds more than one value")
+	}
+}
+
+func TestUnmarshalExtra(t *testing.T) {
+	type withExtra struct {
+		B     string                `bencode:"b"`
+		D     int                   `bencode:"d,omitempty"`
+		Extra map[string]RawMessage `bencode:",extra"`
+	}
+
+	tests := []struct {
+		input    string
+		expected withExtra
+	}{
+		{"d1:b1:xe", withExtra{B: "x"}},
+		// unknown keys end up in Extra and are written back in between the fields
+		{
+			"d1:ai1e1:b1:x1:cl1:ye1:di4e1:ed1:fi0eee",
+			withExtra{B: "x", D: 4, Extra: map[string]RawMessage{"a": RawMessage("i1e"), "c": RawMessage("l1:ye"), "e": RawMessage("d1:fi0ee")}},
+		},
+		// the empty key has no field
+		{"d0:i1e1:b1:xe", withExtra{B: "x", Extra: map[string]RawMessage{"": RawMessage("i1e")}}},
+		// a key that only differs in case from a field is not that field
+		{"d1:Bi1e1:b1:xe", withExtra{B: "x", Extra: map[string]RawMessage{"B": RawMessage("i1e")}}},
+	}
+
+	for _, test := range tests {
+		var res withExtra
+		if err := Unmarshal([]byte(test.input), &res); err != nil {
+			t.Fatalf("expected no error for %s got %s instead", test.input, err)
+		}
+		if !reflect.DeepEqual(res, test.expected) {
+			t.Errorf("expected %+v for %s got %+v instead", test.expected, test.input, res)
+		}
+
+		b, err := Marshal(res)
+		if err != nil {
+			t.Fatalf("expected no error got %s instead", err)
+		}
+		if string(b) != test.input {
+			t.Errorf("expected %s to round trip got %s instead", test.input, b)
+		}
+	}
+
+	// fields win over extra keys with the same name
+	b, _ := Marshal(withExtra{B: "x", Extra: map[string]RawMessage{"b": RawMessage("i1e")}})
+	if string(b) != "d1:b1:xe" {
+		t.Errorf("expected the field to be written instead of the extra key got %s instead", b)
+	}
+}
+
+func TestUnmarshalStrict(t *testing.T) {
+	tests := []struct {
+		input       string
+		validStrict bool
+	}{
+		{input: "i0e", validStrict: true},
+		{input: "i-1e", validStrict: true},
+		{input: "i10e", validStrict: true},
+		{input: "i-0e", validStrict: false},
+		{input: "i03e", validStrict: false},
+		{input: "i-03e", validStrict: false},
+		{input: "0:", validStrict: true},
+		{input: "03:abc", validStrict: false},
+		{input: "d1:ai1e1:bi2ee", validStrict: true},
+		// keys should be sorted as raw byte strings
+		{input: "d1:Bi1e1:ai2ee", validStrict: true},
+		{input: "d1:bi1e1:ai2ee", validStrict: false},
+		{input: "d1:ai1e1:ai2ee", validStrict: false},
+		{input: "ld1:bi1e1:ai2eee", validStrict: false},
+	}
+
+	for _, test := range tests {
+		var res any
+		if err := Unmarshal([]byte(test.input), &res); err != nil {
+			t.Errorf("expected lenient mode to accept %s got %s instead", test.input, err)
+		}
+
+		err := UnmarshalStrict([]byte(test.input), &res)
+		if test.validStrict && err != nil {
+			t.Errorf("expected strict mode to accept %s got %s instead", test.input, err)
+		}
+
+		if !test.validStrict {
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Errorf("expected strict mode to reject %s with a *SyntaxError got %v instead", test.input, err)
+			}
+		}
+	}
+
+	// struct decoding should be checked as well
+	var s struct{ A, B int }
+	if err := UnmarshalStrict([]byte("d1:Bi1e1:Ai2ee"), &s); err == nil {
+		t.Errorf("expected strict mode to reject unsorted keys when decoding into a struct")
+	}
+}
+
+func TestSyntaxError(t *testing.T) {
+	tests := []struct {
+		input     string
+		offset    int64
+		truncated bool
+	}{
+		{input: "", offset: 0, truncated: true},
+		{input: "i12", offset: 3, truncated: true},
+		{input: "5:ab", offset: 4, truncated: true},
+		{input: "d1:a", offset: 4, truncated: true},
+		{input: "li1e", offset: 4, truncated: true},
+		{input: "i1x", offset: 2, truncated: false},
+		{input: "i+1e", offset: 1, truncated: false},
+		{input: "x", offset: 0, truncated: false},
+		{input: "d1:ax", offset: 4, truncated: false},
+		{input: "i1ei2e", offset: 3, truncated: false},
+	}
+
+	for _, test := range tests {
+		var res any
+		err := Unmarshal([]byte(test.input), &res)
+
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("input = %s expected a *SyntaxError got %v instead", test.input, err)
+			continue
+		}
+
+		if syntaxErr.Offset != test.offset {
+			t.Errorf("input = %s expected offset %d got %d instead", test.input, test.offset, syntaxErr.Offset)
+		}
+
+		if errors.Is(err, io.ErrUnexpectedEOF) != test.truncated {
+			t.Errorf("input = %s expected truncated to be %t got %s instead", test.input, test.truncated, err)
+		}
+	}
+}
+
+// materialize turns the Value returned by a consumeXXX func into its generic
+// representation so tests can compare it, it returns the zero value on error.
+func materialize[T any](t *testing.T, val Value, err error) T {
+	var res T
+	if err != nil {
+		return res
+	}
+
+	v, err := val.Interface()
+	if err != nil {
+		t.Fatalf("expected no error materializing %s got %s instead", val.Raw(), err)
+	}
+
+	return v.(T)
+}
+
+func TestUnmarshaler(t *testing.T) {
+	var res struct {
+		C  csv   `bencode:"c"`
+		CP *csv  `bencode:"cp"`
+		CL []csv `bencode:"cl"`
+	}
+
+	if err := Unmarshal([]byte("d1:cl1:a1:be2:cpl1:xe2:clll1:1eee"), &res); err != nil {
+		t.Fatalf("expected no error got %s instead", err)
+	}
+
+	if res.C != "a,b" || res.CP == nil || *res.CP != "x" || len(res.CL) != 1 || res.CL[0] != "1" {
+		t.Errorf("unexpected result %+v", res)
+	}
+
+	if err := Unmarshal([]byte("d1:ci1ee"), &res); err == nil {
+		t.Errorf("expected the error of UnmarshalBencode to be returned")
+	}
+}
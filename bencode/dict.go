@@ -0,0 +1,178 @@
+package bencode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// OrderedDict is a dict that remembers the order of its keys.
+//
+// Decoding into an OrderedDict (or into an empty interface with Decoder.UseOrderedDict)
+// keeps every nested dict as a *OrderedDict, and keeps ints & strings that are not
+// in canonical form (e.g. "i03e") as a RawMessage, so encoding it gives back the exact same bytes.
+// This is what lets editing tools change a single key of a file without touching anything else.
+// Keys are encoded in the order they are stored and not sorted.
+type OrderedDict struct {
+	entries []DictEntry
+}
+
+type DictEntry struct {
+	Key   string
+	Value any
+	// rawKey is the key as it was decoded when it's not in canonical form (e.g. "01:a"),
+	// it's written back as is as long as Key doesn't change
+	rawKey []byte
+}
+
+func NewOrderedDict() *OrderedDict {
+	return &OrderedDict{}
+}
+
+// Get returns the value of key, the last one wins if the key was found more than once.
+func (d *OrderedDict) Get(key string) (any, bool) {
+	i := d.index(key)
+	if i == -1 {
+		return nil, false
+	}
+
+	return d.entries[i].Value, true
+}
+
+// Set replaces the value of key in place or appends it at the end when it's a new key.
+func (d *OrderedDict) Set(key string, val any) {
+	if i := d.index(key); i != -1 {
+		d.entries[i].Value = val
+		return
+	}
+
+	d.entries = append(d.entries, DictEntry{Key: key, Value: val})
+}
+
+// SetSorted is like Set but a new key is inserted before the first key that sorts after it,
+// so a dict with sorted keys stays sorted.
+func (d *OrderedDict) SetSorted(key string, val any) {
+	if i := d.index(key); i != -1 {
+		d.entries[i].Value = val
+		return
+	}
+
+	i := 0
+	for i < len(d.entries) && d.entries[i].Key < key {
+		i++
+	}
+	d.entries = slices.Insert(d.entries, i, DictEntry{Key: key, Value: val})
+}
+
+// Delete removes every occurrence of key.
+func (d *OrderedDict) Delete(key string) {
+	entries := d.entries[:0]
+	for _, e := range d.entries {
+		if e.Key != key {
+			entries = append(entries, e)
+		}
+	}
+	d.entries = entries
+}
+
+// Keys returns the keys in order.
+func (d *OrderedDict) Keys() []string {
+	keys := make([]string, len(d.entries))
+	for i, e := range d.entries {
+		keys[i] = e.Key
+	}
+	return keys
+}
+
+// Entries returns the key value pairs in order.
+func (d *OrderedDict) Entries() []DictEntry {
+	return d.entries
+}
+
+func (d *OrderedDict) Len() int {
+	return len(d.entries)
+}
+
+func (d *OrderedDict) index(key string) int {
+	for i := len(d.entries) - 1; i >= 0; i-- {
+		if d.entries[i].Key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func (d OrderedDict) MarshalBencode() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+
+	buf.WriteByte('d')
+	for _, e := range d.entries {
+		// skipped like the nil values of a map, see Marshal
+		if e.Value == nil || isNil(reflect.ValueOf(e.Value)) {
+			continue
+		}
+
+		if e.rawKey != nil && e.keyMatchesRaw() {
+			buf.Write(e.rawKey)
+		} else {
+			marshalString(&buf, e.Key)
+		}
+		if err := enc.Encode(e.Value); err != nil {
+			return nil, errors.New(fmt.Sprintf("Cannot encode dict key '%s': %s", e.Key, err))
+		}
+	}
+	buf.WriteByte('e')
+
+	return buf.Bytes(), nil
+}
+
+func (d *OrderedDict) UnmarshalBencode(data []byte) error {
+	val, err := Parse(data)
+	if err != nil {
+		return err
+	}
+
+	if val.Kind() != Dict {
+		return errors.New(fmt.Sprintf("Cannot decode bencode %s into an OrderedDict", val.Kind()))
+	}
+
+	state := decodeState{data: data, ordered: true}
+	v, err := state.materializeOrdered(val)
+	if err != nil {
+		return err
+	}
+
+	*d = *v
+	return nil
+}
+
+// materializeOrdered is the *OrderedDict counterpart of the map[string]any built by materialize.
+func (d *decodeState) materializeOrdered(val Value) (*OrderedDict, error) {
+	dict := NewOrderedDict()
+	var err error
+	val.rangeEntries(func(key, elem Value) bool {
+		var v any
+		v, err = d.materialize(elem)
+		// don't use Set so duplicated keys are kept as well
+		entry := DictEntry{Key: string(key.Bytes()), Value: v}
+		if !key.canonical() {
+			entry.rawKey = bytes.Clone(key.Raw())
+		}
+		dict.entries = append(dict.entries, entry)
+		return err == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return dict, nil
+}
+
+// keyMatchesRaw reports whether rawKey still holds Key, the entry could have been built by hand.
+func (e DictEntry) keyMatchesRaw() bool {
+	_, key, ok := bytes.Cut(e.rawKey, []byte(":"))
+	return ok && string(key) == e.Key
+}
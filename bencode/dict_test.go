@@ -0,0 +1,113 @@
+package bencode
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOrderedDictRoundTrip(t *testing.T) {
+	tests := []string{
+		"de",
+		"d1:bi1e1:ai2ee",
+		// nested dicts should keep their order as well
+		"d4:infod6:lengthi1e4:name1:ne8:announce3:urle",
+		"d1:ald1:zi1e1:yi2eeee",
+		// non canonical ints & strings
+		"d1:bi03e1:a02:hie",
+		// duplicated keys
+		"d1:ai1e1:ai2ee",
+		// non canonical keys
+		"d01:ai1e002:bci2ee",
+	}
+
+	for _, test := range tests {
+		var d OrderedDict
+		if err := Unmarshal([]byte(test), &d); err != nil {
+			t.Fatalf("expected no error for %s got %s instead", test, err)
+		}
+
+		res, err := Marshal(&d)
+		if err != nil {
+			t.Fatalf("expected no error for %s got %s instead", test, err)
+		}
+
+		if string(res) != test {
+			t.Errorf("expected %s to round trip got %s instead", test, res)
+		}
+	}
+}
+
+func TestOrderedDict(t *testing.T) {
+	var d OrderedDict
+	if err := Unmarshal([]byte("d8:announce3:url4:infod4:name1:ne7:comment2:hie"), &d); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(d.Keys(), []string{"announce", "info", "comment"}) {
+		t.Errorf("unexpected keys %+v", d.Keys())
+	}
+
+	info, ok := d.Get("info")
+	if _, isDict := info.(*OrderedDict); !ok || !isDict {
+		t.Errorf("expected info to be an *OrderedDict got %T instead", info)
+	}
+
+	// Set replaces in place and appends new keys
+	d.Set("announce", "http://new")
+	d.Set("created by", "me")
+	d.Delete("comment")
+
+	res, err := Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "d8:announce10:http://new4:infod4:name1:ne10:created by2:mee"
+	if string(res) != expected {
+		t.Errorf("expected %s got %s instead", expected, res)
+	}
+
+	if _, ok := d.Get("comment"); ok {
+		t.Errorf("expected comment to be deleted")
+	}
+
+	if d.Len() != 3 {
+		t.Errorf("expected 3 keys got %d instead", d.Len())
+	}
+
+	// SetSorted keeps the keys sorted
+	var sorted OrderedDict
+	Unmarshal([]byte("d1:bi1e1:di2ee"), &sorted)
+	sorted.SetSorted("c", 3)
+	sorted.SetSorted("a", 0)
+	sorted.SetSorted("e", 4)
+	sorted.SetSorted("b", 5)
+	if res, _ := Marshal(sorted); string(res) != "d1:ai0e1:bi5e1:ci3e1:di2e1:ei4ee" {
+		t.Errorf("expected sorted keys got %s instead", res)
+	}
+
+	if err := Unmarshal([]byte("li1ee"), &d); err == nil {
+		t.Errorf("expected an error when decoding a list into an OrderedDict")
+	}
+}
+
+func TestDecoderUseOrderedDict(t *testing.T) {
+	dec := NewDecoder(strings.NewReader("ld1:bi1e1:ai2eee"))
+	dec.UseOrderedDict()
+
+	var res any
+	if err := dec.Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+
+	list, _ := res.([]any)
+	if len(list) != 1 {
+		t.Fatalf("expected a list of one element got %+v", res)
+	}
+
+	d, ok := list[0].(*OrderedDict)
+	if !ok || !reflect.DeepEqual(d.Keys(), []string{"b", "a"}) {
+		t.Errorf("expected an *OrderedDict with keys b, a got %+v", list[0])
+	}
+}
@@ -0,0 +1,150 @@
+package bencode
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+)
+
+type ChangeKind int
+
+const (
+	Added ChangeKind = iota
+	Removed
+	Changed
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	default:
+		return "changed"
+	}
+}
+
+// Change is a single difference found by Diff.
+// Old is invalid for added values and New is invalid for removed ones.
+type Change struct {
+	Kind ChangeKind
+	Path string // same syntax as Value.Lookup, empty for the top level value
+	Old  Value
+	New  Value
+}
+
+// maxDiffBytes is how many bytes of a binary string Change.String shows before cutting it,
+// otherwise a changed "pieces" would print megabytes of hex.
+const maxDiffBytes = 32
+
+func (c Change) String() string {
+	path := c.Path
+	if path == "" {
+		path = "(root)"
+	}
+
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("+ %s: %s", path, formatDiffValue(c.New))
+	case Removed:
+		return fmt.Sprintf("- %s: %s", path, formatDiffValue(c.Old))
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", path, formatDiffValue(c.Old), formatDiffValue(c.New))
+	}
+}
+
+// formatDiffValue shows strings that are not valid UTF-8 as hex and everything else as JSON.
+func formatDiffValue(val Value) string {
+	if val.Kind() == String && !utf8.Valid(val.Bytes()) {
+		str := val.Bytes()
+		if len(str) > maxDiffBytes {
+			return fmt.Sprintf("0x%s... (%d bytes)", hex.EncodeToString(str[:maxDiffBytes]), len(str))
+		}
+		return "0x" + hex.EncodeToString(str)
+	}
+
+	js, err := ToJSON(val.Raw())
+	if err != nil {
+		return string(val.Raw())
+	}
+	return string(js)
+}
+
+// Diff compares two bencode values and returns what was added, removed or changed to go from a to b.
+// Dicts are compared key by key (in the order of a, then the keys only found in b)
+// and lists index by index, anything else (including values of different kinds) is reported as changed
+// when the raw bytes differ.
+func Diff(a, b Value) []Change {
+	changes := make([]Change, 0)
+	return diffValues(changes, nil, a, b)
+}
+
+func diffValues(changes []Change, path []string, a, b Value) []Change {
+	if bytes.Equal(a.Raw(), b.Raw()) {
+		return changes
+	}
+
+	switch {
+	case a.Kind() == Dict && b.Kind() == Dict:
+		seen := make(map[string]bool)
+		a.Range(func(key []byte, _ Value) bool {
+			k := string(key)
+			if seen[k] {
+				return true
+			}
+			seen[k] = true
+
+			keyPath := append(path[:len(path):len(path)], k)
+			if newVal := b.Get(k); newVal.Exists() {
+				changes = diffValues(changes, keyPath, a.Get(k), newVal)
+			} else {
+				changes = append(changes, Change{Kind: Removed, Path: JoinPath(keyPath), Old: a.Get(k)})
+			}
+			return true
+		})
+
+		b.Range(func(key []byte, _ Value) bool {
+			k := string(key)
+			if seen[k] {
+				return true
+			}
+			seen[k] = true
+
+			keyPath := append(path[:len(path):len(path)], k)
+			changes = append(changes, Change{Kind: Added, Path: JoinPath(keyPath), New: b.Get(k)})
+			return true
+		})
+
+	case a.Kind() == List && b.Kind() == List:
+		aElems, bElems := listElements(a), listElements(b)
+		for i := 0; i < max(len(aElems), len(bElems)); i++ {
+			idxPath := append(path[:len(path):len(path)], strconv.Itoa(i))
+			switch {
+			case i >= len(aElems):
+				changes = append(changes, Change{Kind: Added, Path: JoinPath(idxPath), New: bElems[i]})
+			case i >= len(bElems):
+				changes = append(changes, Change{Kind: Removed, Path: JoinPath(idxPath), Old: aElems[i]})
+			default:
+				changes = diffValues(changes, idxPath, aElems[i], bElems[i])
+			}
+		}
+
+	default:
+		changes = append(changes, Change{Kind: Changed, Path: JoinPath(path), Old: a, New: b})
+	}
+
+	return changes
+}
+
+// listElements collects the elements of a list once instead of calling Index (which walks the list) for each of them.
+func listElements(list Value) []Value {
+	elems := make([]Value, 0)
+	list.Range(func(_ []byte, elem Value) bool {
+		elems = append(elems, elem)
+		return true
+	})
+	return elems
+}
@@ -0,0 +1,76 @@
+package bencode
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected []string
+	}{
+		{"d1:ai1ee", "d1:ai1ee", []string{}},
+		{"i1e", "i2e", []string{"~ (root): 1 -> 2"}},
+		{"i1e", "1:a", []string{`~ (root): 1 -> "a"`}},
+		{
+			"d8:announce3:old7:comment2:hi4:infod4:name1:nee",
+			"d8:announce3:new4:infod4:name1:n7:privatei1eee",
+			[]string{
+				`~ announce: "old" -> "new"`,
+				`- comment: "hi"`,
+				"+ info.private: 1",
+			},
+		},
+		{
+			"d1:lli1ei2ei3eee",
+			"d1:lli1ei5eee",
+			[]string{"~ l.1: 2 -> 5", "- l.2: 3"},
+		},
+		{
+			"ll1:aee",
+			"ll1:ael1:bee",
+			[]string{`+ 1: ["b"]`},
+		},
+		// binary strings are shown as hex
+		{"d6:pieces2:\xff\x02e", "d6:pieces2:\xff\x03e", []string{"~ pieces: 0xff02 -> 0xff03"}},
+		{"d3:a.bi1ee", "d3:a.bi2ee", []string{`~ a\.b: 1 -> 2`}},
+		// duplicated keys are compared once using the last value
+		{"d1:ai1e1:ai2ee", "d1:ai3ee", []string{"~ a: 2 -> 3"}},
+	}
+
+	for _, test := range tests {
+		a, err := Parse([]byte(test.a))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := Parse([]byte(test.b))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res := make([]string, 0)
+		for _, change := range Diff(a, b) {
+			res = append(res, change.String())
+		}
+
+		if !reflect.DeepEqual(res, test.expected) {
+			t.Errorf("expected diff of %q and %q to be %q got %q instead", test.a, test.b, test.expected, res)
+		}
+	}
+}
+
+func TestDiffLongBinary(t *testing.T) {
+	a, _ := Parse([]byte("40:\xff234567890123456789012345678901234567890"))
+	b, _ := Parse([]byte("i1e"))
+
+	changes := Diff(a, b)
+	if len(changes) != 1 || changes[0].Kind != Changed {
+		t.Fatalf("expected a single change got %v instead", changes)
+	}
+
+	expected := "~ (root): 0xff32333435363738393031323334353637383930313233343536373839303132... (40 bytes) -> 1"
+	if res := changes[0].String(); res != expected {
+		t.Errorf("expected %s got %s instead", expected, res)
+	}
+}
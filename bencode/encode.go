@@ -0,0 +1,276 @@
+package bencode
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// Marshal returns the canonical bencoding of v.
+//
+// Structs are encoded as dicts, each exported field becomes a key named after
+// its `bencode` tag (or the field name when there is no tag):
+//
+//	PieceLength int `bencode:"piece length"`
+//	Private     bool `bencode:"private,omitempty"`
+//	Ignored     int  `bencode:"-"`
+//
+// "omitempty" skips zero values. A map tagged `bencode:",extra"` has its keys written
+// next to the fields, which is how keys unknown to a struct survive a round-trip.
+// Bencode has no null so nil pointers, interfaces and maps are left out of dicts
+// (struct fields, map values and OrderedDict entries), a missing key being how an unset value
+// is written. Nil pointers and interfaces are an error in lists where dropping them would shift
+// the other elements, a nil map there is an empty dict.
+// Ints of every width, big.Int, bools (as 0/1), strings, []byte, [N]byte, slices, arrays,
+// maps with string keys and pointers to any of those are supported.
+// Dict keys are always written sorted as raw byte strings.
+// Types implementing BencodeMarshaler encode themselves.
+func Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// An Encoder writes bencode values to an output stream.
+type Encoder struct {
+	w *bufio.Writer
+}
+
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: bufio.NewWriter(w)}
+}
+
+// Encode writes the canonical bencoding of v to the stream, see Marshal for the supported types.
+// The output is written as it's produced so large values (e.g. the "pieces" of a torrent)
+// are never held in memory twice. When an error is returned part of the value may
+// have already been written.
+func (e *Encoder) Encode(v any) error {
+	if err := marshalValue(e.w, reflect.ValueOf(v)); err != nil {
+		return err
+	}
+
+	return e.w.Flush()
+}
+
+// encodeWriter is what the "marshalXXX" funcs write to, bufio.Writer
+// keeps the first write error and returns it on Flush so they don't need to check it.
+type encodeWriter interface {
+	io.Writer
+	io.ByteWriter
+	io.StringWriter
+}
+
+func marshalValue(w encodeWriter, rv reflect.Value) error {
+	if !rv.IsValid() {
+		return errors.New("Cannot encode a nil value")
+	}
+
+	if rv.Type() == rawMessageType {
+		return marshalRaw(w, rv.Bytes())
+	}
+
+	if m, ok := marshaler(rv); ok {
+		return marshalCustom(w, m)
+	}
+
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return errors.New(fmt.Sprintf("Cannot encode a nil %s", rv.Type()))
+		}
+		return marshalValue(w, rv.Elem())
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		w.WriteByte('i')
+		w.WriteString(strconv.FormatInt(rv.Int(), 10))
+		w.WriteByte('e')
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		w.WriteByte('i')
+		w.WriteString(strconv.FormatUint(rv.Uint(), 10))
+		w.WriteByte('e')
+
+	case reflect.Bool:
+		if rv.Bool() {
+			w.WriteString("i1e")
+		} else {
+			w.WriteString("i0e")
+		}
+
+	case reflect.String:
+		marshalString(w, rv.String())
+
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			marshalBytes(w, rv.Bytes())
+			return nil
+		}
+		return marshalList(w, rv)
+
+	case reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(b), rv)
+			marshalBytes(w, b)
+			return nil
+		}
+		return marshalList(w, rv)
+
+	case reflect.Map:
+		return marshalMap(w, rv)
+
+	case reflect.Struct:
+		if rv.Type() == bigIntType {
+			marshalBigInt(w, rv)
+			return nil
+		}
+		return marshalStruct(w, rv)
+
+	default:
+		return errors.New(fmt.Sprintf("Cannot encode value of type %s", rv.Type()))
+	}
+
+	return nil
+}
+
+func marshalString(w encodeWriter, s string) {
+	w.WriteString(strconv.Itoa(len(s)))
+	w.WriteByte(':')
+	w.WriteString(s)
+}
+
+func marshalBytes(w encodeWriter, b []byte) {
+	w.WriteString(strconv.Itoa(len(b)))
+	w.WriteByte(':')
+	w.Write(b)
+}
+
+func marshalList(w encodeWriter, rv reflect.Value) error {
+	w.WriteByte('l')
+	for i := range rv.Len() {
+		if err := marshalValue(w, rv.Index(i)); err != nil {
+			return errors.New(fmt.Sprintf("Cannot encode list element %d: %s", i, err))
+		}
+	}
+	w.WriteByte('e')
+
+	return nil
+}
+
+func marshalMap(w encodeWriter, rv reflect.Value) error {
+	if rv.Type().Key().Kind() != reflect.String {
+		return errors.New(fmt.Sprintf("Cannot encode %s, dict keys must be strings", rv.Type()))
+	}
+
+	keys := make([]string, 0, rv.Len())
+	for _, k := range rv.MapKeys() {
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+
+	w.WriteByte('d')
+	for _, k := range keys {
+		val := rv.MapIndex(reflect.ValueOf(k).Convert(rv.Type().Key()))
+		if isNil(val) {
+			continue
+		}
+
+		marshalString(w, k)
+		if err := marshalValue(w, val); err != nil {
+			return errors.New(fmt.Sprintf("Cannot encode dict key '%s': %s", k, err))
+		}
+	}
+	w.WriteByte('e')
+
+	return nil
+}
+
+func marshalStruct(w encodeWriter, rv reflect.Value) error {
+	fields := cachedFields(rv.Type())
+	extra := extraField(fields)
+	if extra == nil || rv.Field(extra.index).Len() == 0 {
+		// the fields are already sorted by key
+		return marshalFields(w, rv, fields)
+	}
+
+	// the extra keys have to be merged with the fields to keep the keys sorted
+	extraMap := rv.Field(extra.index)
+	entries := make([]field, 0, len(fields)+extraMap.Len())
+	known := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		if !f.extra {
+			entries = append(entries, f)
+			known[f.name] = true
+		}
+	}
+	for _, key := range extraMap.MapKeys() {
+		// a field always takes precedence over an extra key with the same name
+		if !known[key.String()] {
+			entries = append(entries, field{name: key.String(), index: -1})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].name < entries[j].name })
+
+	w.WriteByte('d')
+	for _, f := range entries {
+		val := extraMap.MapIndex(reflect.ValueOf(f.name).Convert(extraMap.Type().Key()))
+		if f.index >= 0 {
+			val = rv.Field(f.index)
+		}
+		if err := marshalField(w, f, val); err != nil {
+			return err
+		}
+	}
+	w.WriteByte('e')
+
+	return nil
+}
+
+func marshalFields(w encodeWriter, rv reflect.Value, fields []field) error {
+	w.WriteByte('d')
+	for _, f := range fields {
+		if f.extra {
+			continue
+		}
+		if err := marshalField(w, f, rv.Field(f.index)); err != nil {
+			return err
+		}
+	}
+	w.WriteByte('e')
+
+	return nil
+}
+
+func marshalField(w encodeWriter, f field, val reflect.Value) error {
+	if isNil(val) || (f.omitEmpty && val.IsZero()) {
+		return nil
+	}
+
+	marshalString(w, f.name)
+	if err := marshalValue(w, val); err != nil {
+		return errors.New(fmt.Sprintf("Cannot encode dict key '%s': %s", f.name, err))
+	}
+	return nil
+}
+
+func isNil(rv reflect.Value) bool {
+	switch rv.Kind() {
+	case reflect.Interface:
+		// an interface holding a nil pointer is just as empty
+		return rv.IsNil() || isNil(rv.Elem())
+	case reflect.Pointer, reflect.Map:
+		return rv.IsNil()
+	case reflect.Slice:
+		// an empty RawMessage holds no value at all
+		return rv.Type() == rawMessageType && rv.Len() == 0
+	}
+	return false
+}
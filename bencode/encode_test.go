@@ -0,0 +1,216 @@
+package bencode
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type testFile struct {
+	Length int64    `bencode:"length"`
+	Path   []string `bencode:"path"`
+	Md5sum string   `bencode:"md5sum,omitempty"`
+}
+
+type testInfo struct {
+	Name        string     `bencode:"name"`
+	PieceLength int32      `bencode:"piece length"`
+	Pieces      []byte     `bencode:"pieces"`
+	Private     bool       `bencode:"private,omitempty"`
+	Files       []testFile `bencode:"files,omitempty"`
+	Ignored     int        `bencode:"-"`
+	ignored     int
+}
+
+type testTorrent struct {
+	Announce     string            `bencode:"announce"`
+	AnnounceList [][]string        `bencode:"announce-list,omitempty"`
+	CreationDate *int64            `bencode:"creation date,omitempty"`
+	Info         *testInfo         `bencode:"info"`
+	Extra        map[string]uint16 `bencode:"extra,omitempty"`
+}
+
+func TestMarshal(t *testing.T) {
+	date := int64(1724947415)
+	tests := []struct {
+		input    any
+		expected string
+	}{
+		{
+			input:    int8(-5),
+			expected: "i-5e",
+		},
+		{
+			input:    uint64(18446744073709551615),
+			expected: "i18446744073709551615e",
+		},
+		{
+			input:    true,
+			expected: "i1e",
+		},
+		{
+			input:    []byte("hey"),
+			expected: "3:hey",
+		},
+		{
+			input:    [2]byte{'h', 'i'},
+			expected: "2:hi",
+		},
+		{
+			input:    []int{1, 2},
+			expected: "li1ei2ee",
+		},
+		{
+			input:    map[string]any{"b": 1, "a": []any{"x"}},
+			expected: "d1:al1:xe1:bi1ee",
+		},
+		// fields should be written sorted by their tag name and not by declaration order
+		{
+			input:    testInfo{Name: "n", PieceLength: 16, Pieces: []byte("abc"), Ignored: 1, ignored: 2},
+			expected: "d4:name1:n12:piece lengthi16e6:pieces3:abce",
+		},
+		{
+			input: &testTorrent{
+				Announce:     "http://t",
+				AnnounceList: [][]string{{"http://t"}},
+				CreationDate: &date,
+				Info: &testInfo{
+					Name:    "dir",
+					Private: true,
+					Files:   []testFile{{Length: 6203355136, Path: []string{"a", "b"}}},
+				},
+				Extra: map[string]uint16{"port": 6881},
+			},
+			expected: "d8:announce8:http://t13:announce-listll8:http://tee13:creation datei1724947415e" +
+				"5:extrad4:porti6881ee4:infod5:filesld6:lengthi6203355136e4:pathl1:a1:beee" +
+				"4:name3:dir12:piece lengthi0e6:pieces0:7:privatei1eee",
+		},
+		// nil pointers are skipped
+		{
+			input:    testTorrent{Announce: "a"},
+			expected: "d8:announce1:ae",
+		},
+		// so are nil dict values
+		{
+			input:    map[string]any{"a": nil, "b": (*int)(nil), "c": map[string]int(nil), "d": 1},
+			expected: "d1:di1ee",
+		},
+		{
+			input: func() *OrderedDict {
+				d := NewOrderedDict()
+				d.Set("a", nil)
+				d.Set("b", 1)
+				d.Set("c", (*int)(nil))
+				return d
+			}(),
+			expected: "d1:bi1ee",
+		},
+	}
+
+	for _, test := range tests {
+		res, err := Marshal(test.input)
+		if err != nil {
+			t.Fatalf("expected no error for input %+v got %s instead", test.input, err)
+		}
+
+		if string(res) != test.expected {
+			t.Errorf("input = %+v expected %s got %s", test.input, test.expected, res)
+		}
+	}
+}
+
+func TestMarshalUnsupported(t *testing.T) {
+	tests := []any{
+		nil,
+		1.5,
+		map[int]string{1: "a"},
+		// nil list elements cannot be skipped without changing the indexes of the others
+		[]any{nil},
+		[]*int{nil},
+		struct{ C chan int }{},
+	}
+
+	for _, test := range tests {
+		if _, err := Marshal(test); err == nil {
+			t.Errorf("expected an error for input %+v", test)
+		}
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	var sb strings.Builder
+	enc := NewEncoder(&sb)
+
+	values := []any{42, "spam", []byte("eggs"), map[string]int{"b": 2, "a": 1}}
+	for _, v := range values {
+		if err := enc.Encode(v); err != nil {
+			t.Fatalf("expected no error got %s instead", err)
+		}
+	}
+
+	expected := "i42e4:spam4:eggsd1:ai1e1:bi2ee"
+	if sb.String() != expected {
+		t.Errorf("expected %s got %s instead", expected, sb.String())
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestEncoderWriteError(t *testing.T) {
+	if err := NewEncoder(failingWriter{}).Encode("spam"); err == nil {
+		t.Errorf("expected the write error to be returned")
+	}
+}
+
+// csv is encoded as a list instead of a comma separated string
+type csv string
+
+func (c csv) MarshalBencode() ([]byte, error) {
+	return Marshal(strings.Split(string(c), ","))
+}
+
+func (c *csv) UnmarshalBencode(data []byte) error {
+	var parts []string
+	if err := Unmarshal(data, &parts); err != nil {
+		return err
+	}
+
+	*c = csv(strings.Join(parts, ","))
+	return nil
+}
+
+type invalidMarshaler struct{}
+
+func (invalidMarshaler) MarshalBencode() ([]byte, error) {
+	return []byte("i1"), nil
+}
+
+func TestMarshaler(t *testing.T) {
+	tests := []struct {
+		input    any
+		expected string
+	}{
+		{input: csv("a,b"), expected: "l1:a1:be"},
+		{input: struct{ C csv }{C: "x"}, expected: "d1:Cl1:xee"},
+		{input: map[string]csv{"k": "1,2"}, expected: "d1:kl1:11:2ee"},
+	}
+
+	for _, test := range tests {
+		res, err := Marshal(test.input)
+		if err != nil {
+			t.Fatalf("expected no error got %s instead", err)
+		}
+
+		if string(res) != test.expected {
+			t.Errorf("input = %+v expected %s got %s", test.input, test.expected, res)
+		}
+	}
+
+	if _, err := Marshal(invalidMarshaler{}); err == nil {
+		t.Errorf("expected an error when MarshalBencode returns invalid bencode")
+	}
+}
@@ -0,0 +1,94 @@
+package bencode
+
+import (
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// field describes how a struct field maps to a bencode dict key.
+type field struct {
+	name      string
+	index     int
+	omitEmpty bool
+	// extra is set on the `bencode:",extra"` map field which holds the keys no other field matched
+	extra bool
+}
+
+// fieldCache maps a reflect.Type to its []field sorted by key
+// so we only walk the struct tags once per type.
+var fieldCache sync.Map
+
+func cachedFields(typ reflect.Type) []field {
+	if f, ok := fieldCache.Load(typ); ok {
+		return f.([]field)
+	}
+
+	f, _ := fieldCache.LoadOrStore(typ, typeFields(typ))
+	return f.([]field)
+}
+
+// typeFields reads the `bencode:"name,omitempty"` tags of typ.
+// Fields without a tag use their Go name as key, fields tagged with "-" and
+// unexported fields are ignored.
+// A map with string keys tagged `bencode:",extra"` gets every key that has no field,
+// the first one wins if there is more than one.
+func typeFields(typ reflect.Type) []field {
+	fields := make([]field, 0, typ.NumField())
+	for i := range typ.NumField() {
+		sf := typ.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		tag := sf.Tag.Get("bencode")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		if slices.Contains(strings.Split(opts, ","), "extra") &&
+			sf.Type.Kind() == reflect.Map && sf.Type.Key().Kind() == reflect.String {
+			fields = append(fields, field{index: i, extra: true})
+			continue
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+
+		fields = append(fields, field{
+			name:      name,
+			index:     i,
+			omitEmpty: slices.Contains(strings.Split(opts, ","), "omitempty"),
+		})
+	}
+
+	// dict keys must be written sorted so keep the fields in that order
+	sort.Slice(fields, func(i, j int) bool { return fields[i].name < fields[j].name })
+
+	return fields
+}
+
+// lookupField returns the field whose key is exactly name,
+// dict keys are byte strings so "Comment" and "comment" are different keys.
+func lookupField(fields []field, name string) *field {
+	for i := range fields {
+		if !fields[i].extra && fields[i].name == name {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+// extraField returns the `bencode:",extra"` field if there is one.
+func extraField(fields []field) *field {
+	for i := range fields {
+		if fields[i].extra {
+			return &fields[i]
+		}
+	}
+	return nil
+}
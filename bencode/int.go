@@ -0,0 +1,125 @@
+package bencode
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+)
+
+// bencode ints have no size limit, they are decoded as int64 by default
+// and math/big is used for anything bigger (see Decoder.UseBigInt).
+
+var (
+	bigIntType = reflect.TypeFor[big.Int]()
+	int64Type  = reflect.TypeFor[int64]()
+)
+
+// IntOverflowError is returned when an int does not fit the Go type it's decoded into.
+type IntOverflowError struct {
+	Value  string // the int as written in the input
+	Offset int64
+	Type   reflect.Type
+}
+
+func (e *IntOverflowError) Error() string {
+	return fmt.Sprintf("Int %s at position '%d' overflows %s", e.Value, e.Offset, e.Type)
+}
+
+// digits returns the text of an int value, e.g. "-42" for "i-42e".
+func (v Value) digits() string {
+	return string(v.buf[v.start+1 : v.end-1])
+}
+
+// Int returns the value of an int, it fails with an *IntOverflowError
+// when the value does not fit an int64 (use BigInt for those).
+func (v Value) Int() (int64, error) {
+	if v.Kind() != Int {
+		return 0, errors.New(fmt.Sprintf("Expected an int at position '%d' got %s instead", v.start, v.Kind()))
+	}
+
+	n, err := strconv.ParseInt(v.digits(), 10, 64)
+	if err != nil {
+		return 0, &IntOverflowError{Value: v.digits(), Offset: int64(v.start), Type: int64Type}
+	}
+
+	return n, nil
+}
+
+// BigInt returns the value of an int whatever its size.
+func (v Value) BigInt() (*big.Int, error) {
+	if v.Kind() != Int {
+		return nil, errors.New(fmt.Sprintf("Expected an int at position '%d' got %s instead", v.start, v.Kind()))
+	}
+
+	// the digits were validated by Parse so this cannot fail
+	n, _ := new(big.Int).SetString(v.digits(), 10)
+	return n, nil
+}
+
+func (d *decodeState) unmarshalInt(val Value, rv reflect.Value) error {
+	overflow := &IntOverflowError{Value: val.digits(), Offset: d.offset(val), Type: rv.Type()}
+
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(val.digits(), 10, 64)
+		if err != nil || rv.OverflowInt(n) {
+			return overflow
+		}
+		rv.SetInt(n)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(val.digits(), 10, 64)
+		if err != nil || rv.OverflowUint(n) {
+			return overflow
+		}
+		rv.SetUint(n)
+
+	case reflect.Bool:
+		// bencode has no booleans, flags such as "private" are ints set to 0 or 1
+		// (and "-0" or "00" are still zero in lenient mode)
+		n, _ := val.BigInt()
+		rv.SetBool(n.Sign() != 0)
+
+	case reflect.Struct:
+		if rv.Type() != bigIntType || !rv.CanAddr() {
+			return d.typeMismatch(val, rv.Type())
+		}
+		n, _ := val.BigInt()
+		rv.Addr().Interface().(*big.Int).Set(n)
+
+	default:
+		return d.typeMismatch(val, rv.Type())
+	}
+
+	return nil
+}
+
+// interfaceInt is what an int decodes to in an empty interface.
+func (d *decodeState) interfaceInt(val Value) (any, error) {
+	n, err := val.Int()
+	// an OrderedDict has to hold every value to be able to write it back
+	if d.bigInts || (err != nil && d.ordered) {
+		return val.BigInt()
+	}
+	if err != nil {
+		return nil, &IntOverflowError{Value: val.digits(), Offset: d.offset(val), Type: int64Type}
+	}
+
+	return n, nil
+}
+
+func marshalBigInt(w encodeWriter, rv reflect.Value) {
+	var n *big.Int
+	if rv.CanAddr() {
+		n = rv.Addr().Interface().(*big.Int)
+	} else {
+		n = new(big.Int)
+		*n = rv.Interface().(big.Int)
+	}
+
+	w.WriteByte('i')
+	w.WriteString(n.String())
+	w.WriteByte('e')
+}
@@ -0,0 +1,148 @@
+package bencode
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+const hugeInt = "123456789012345678901234567890"
+
+func TestUnmarshalInt64(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"i9223372036854775807e", math.MaxInt64},
+		{"i-9223372036854775808e", math.MinInt64},
+		// files over 2GiB
+		{"i6203355136e", 6203355136},
+	}
+
+	for _, test := range tests {
+		var res int64
+		if err := Unmarshal([]byte(test.input), &res); err != nil {
+			t.Fatalf("expected no error for %s got %s instead", test.input, err)
+		}
+		if res != test.expected {
+			t.Errorf("expected %d got %d instead", test.expected, res)
+		}
+
+		var generic any
+		if err := Unmarshal([]byte(test.input), &generic); err != nil {
+			t.Fatalf("expected no error for %s got %s instead", test.input, err)
+		}
+		if generic != test.expected {
+			t.Errorf("expected %d got %v (%T) instead", test.expected, generic, generic)
+		}
+	}
+
+	var u uint64
+	if err := Unmarshal([]byte("i18446744073709551615e"), &u); err != nil || u != math.MaxUint64 {
+		t.Errorf("expected max uint64 got %d (%v) instead", u, err)
+	}
+}
+
+func TestIntOverflow(t *testing.T) {
+	var (
+		i8      int8
+		i64     int64
+		u64     uint64
+		generic any
+	)
+
+	tests := []struct {
+		input  string
+		target any
+		offset int64
+	}{
+		{"i128e", &i8, 0},
+		{"i9223372036854775808e", &i64, 0},
+		{"i-1e", &u64, 0},
+		{"i18446744073709551616e", &u64, 0},
+		{"li1ei" + hugeInt + "ee", &generic, 4},
+	}
+
+	for _, test := range tests {
+		err := Unmarshal([]byte(test.input), test.target)
+
+		var overflow *IntOverflowError
+		if !errors.As(err, &overflow) {
+			t.Errorf("expected an *IntOverflowError for %s got %v instead", test.input, err)
+			continue
+		}
+
+		if overflow.Offset != test.offset {
+			t.Errorf("expected the overflow of %s at %d got %d instead", test.input, test.offset, overflow.Offset)
+		}
+		if strings.Contains(err.Error(), "strconv") {
+			t.Errorf("expected a clean error message got %s instead", err)
+		}
+	}
+}
+
+func TestBigInt(t *testing.T) {
+	var res struct {
+		N  big.Int  `bencode:"n"`
+		NP *big.Int `bencode:"np"`
+	}
+
+	input := "d1:ni" + hugeInt + "e2:npi-" + hugeInt + "ee"
+	if err := Unmarshal([]byte(input), &res); err != nil {
+		t.Fatalf("expected no error got %s instead", err)
+	}
+
+	if res.N.String() != hugeInt || res.NP.String() != "-"+hugeInt {
+		t.Errorf("unexpected big ints %s and %s", res.N.String(), res.NP.String())
+	}
+
+	encoded, err := Marshal(res)
+	if err != nil {
+		t.Fatalf("expected no error got %s instead", err)
+	}
+	if string(encoded) != input {
+		t.Errorf("expected %s got %s instead", input, encoded)
+	}
+
+	n, _ := new(big.Int).SetString(hugeInt, 10)
+	if encoded, _ := Marshal(*n); string(encoded) != "i"+hugeInt+"e" {
+		t.Errorf("expected a big.Int value to be encoded as an int got %s instead", encoded)
+	}
+}
+
+func TestDecoderUseBigInt(t *testing.T) {
+	dec := NewDecoder(strings.NewReader("li1ei" + hugeInt + "ee"))
+	dec.UseBigInt()
+
+	var res any
+	if err := dec.Decode(&res); err != nil {
+		t.Fatalf("expected no error got %s instead", err)
+	}
+
+	list := res.([]any)
+	if list[0].(*big.Int).Int64() != 1 || list[1].(*big.Int).String() != hugeInt {
+		t.Errorf("unexpected result %v", res)
+	}
+}
+
+func TestHugeIntRoundTrip(t *testing.T) {
+	input := "d1:bi" + hugeInt + "e1:ai1ee"
+
+	var d OrderedDict
+	if err := Unmarshal([]byte(input), &d); err != nil {
+		t.Fatalf("expected no error got %s instead", err)
+	}
+	if res, _ := Marshal(d); string(res) != input {
+		t.Errorf("expected %s to round trip through an OrderedDict got %s instead", input, res)
+	}
+
+	js, err := ToJSON([]byte(input))
+	if err != nil {
+		t.Fatalf("expected no error got %s instead", err)
+	}
+	if res, _ := FromJSON(js); string(res) != input {
+		t.Errorf("expected %s to round trip through JSON got %s instead", input, res)
+	}
+}
@@ -0,0 +1,292 @@
+package bencode
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// JSON conversion markers, see ToJSON.
+const (
+	jsonHexKey    = "$hex"
+	jsonRawKey    = "$raw"
+	jsonKeyPrefix = "$hex:"
+	jsonRawPrefix = "$raw:"
+)
+
+// ToJSON converts a bencode document into JSON that FromJSON turns back into the exact same bytes.
+//
+//   - ints become JSON numbers (whatever their size) and lists become arrays
+//   - strings that are valid UTF-8 become JSON strings, binary strings (e.g. "pieces")
+//     become {"$hex": "<hex encoded bytes>"}
+//   - dicts become objects with their keys in the original order, keys that are not
+//     valid UTF-8 or that start with "$" are written as "$hex:<hex encoded key>"
+//     and keys not in canonical form (e.g. "01:a") as "$raw:<hex encoded bencode>"
+//   - ints & strings not in canonical form (e.g. "i03e") become {"$raw": "<hex encoded bencode>"}
+//
+// Since a real key can never start with "$", an object holding a "$hex" or "$raw" key
+// is always one of the markers above.
+func ToJSON(data []byte) ([]byte, error) {
+	val, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, val); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeJSON(buf *bytes.Buffer, val Value) error {
+	switch val.Kind() {
+	case Int:
+		if !val.canonical() {
+			writeJSONMarker(buf, jsonRawKey, val.Raw())
+			return nil
+		}
+		// canonical ints are valid JSON numbers whatever their size
+		buf.WriteString(val.digits())
+
+	case String:
+		str := val.Bytes()
+		switch {
+		case !val.canonical():
+			writeJSONMarker(buf, jsonRawKey, val.Raw())
+		case !utf8.Valid(str):
+			writeJSONMarker(buf, jsonHexKey, str)
+		default:
+			writeJSONString(buf, string(str))
+		}
+
+	case List:
+		buf.WriteByte('[')
+		i := 0
+		var err error
+		val.Range(func(_ []byte, elem Value) bool {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			i++
+
+			err = writeJSON(buf, elem)
+			return err == nil
+		})
+		if err != nil {
+			return err
+		}
+		buf.WriteByte(']')
+
+	case Dict:
+		buf.WriteByte('{')
+		i := 0
+		var err error
+		val.rangeEntries(func(keyVal, elem Value) bool {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			i++
+
+			key := keyVal.Bytes()
+			switch {
+			case !keyVal.canonical():
+				writeJSONString(buf, jsonRawPrefix+hex.EncodeToString(keyVal.Raw()))
+			case !utf8.Valid(key) || bytes.HasPrefix(key, []byte("$")):
+				writeJSONString(buf, jsonKeyPrefix+hex.EncodeToString(key))
+			default:
+				writeJSONString(buf, string(key))
+			}
+			buf.WriteByte(':')
+
+			err = writeJSON(buf, elem)
+			return err == nil
+		})
+		if err != nil {
+			return err
+		}
+		buf.WriteByte('}')
+
+	default:
+		return errors.New("Cannot convert an invalid value to JSON")
+	}
+
+	return nil
+}
+
+func writeJSONMarker(buf *bytes.Buffer, marker string, b []byte) {
+	buf.WriteByte('{')
+	writeJSONString(buf, marker)
+	buf.WriteByte(':')
+	writeJSONString(buf, hex.EncodeToString(b))
+	buf.WriteByte('}')
+}
+
+func writeJSONString(buf *bytes.Buffer, s string) {
+	// unlike json.Marshal this keeps '<', '>' and '&' as is which is easier to read
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	buf.Truncate(buf.Len() - 1) // Encode always adds a new line
+}
+
+// FromJSON converts a JSON document produced by ToJSON (or written by hand following
+// the same conventions) back into bencode.
+// Floats, booleans and null have no bencode equivalent and are rejected.
+func FromJSON(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	val, err := readJSON(dec)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("Unexpected data after the top level JSON value")
+	}
+
+	return Marshal(val)
+}
+
+// readJSON reads the next JSON value token by token so objects keep their key order.
+func readJSON(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := tok.(type) {
+	case json.Number:
+		if n, err := strconv.ParseInt(t.String(), 10, 64); err == nil {
+			return n, nil
+		}
+
+		n, ok := new(big.Int).SetString(t.String(), 10)
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("Cannot convert JSON number %s to a bencode int", t))
+		}
+		return n, nil
+
+	case string:
+		return t, nil
+
+	case json.Delim:
+		if t == '[' {
+			list := make([]any, 0)
+			for dec.More() {
+				v, err := readJSON(dec)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, v)
+			}
+
+			_, err := dec.Token() // the closing ']'
+			return list, err
+		}
+
+		return readJSONObject(dec)
+	}
+
+	return nil, errors.New(fmt.Sprintf("Cannot convert JSON %v to bencode", tok))
+}
+
+func readJSONObject(dec *json.Decoder) (any, error) {
+	dict := NewOrderedDict()
+	for i := 0; dec.More(); i++ {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key := tok.(string)
+
+		if i == 0 && (key == jsonHexKey || key == jsonRawKey) {
+			return readJSONMarker(dec, key)
+		}
+
+		entry := DictEntry{Key: key}
+		if strings.HasPrefix(key, "$") {
+			entry, err = readJSONKey(key)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		entry.Value, err = readJSON(dec)
+		if err != nil {
+			return nil, err
+		}
+		dict.entries = append(dict.entries, entry)
+	}
+
+	_, err := dec.Token() // the closing '}'
+	return dict, err
+}
+
+// readJSONKey decodes a "$hex:" or "$raw:" key.
+func readJSONKey(key string) (DictEntry, error) {
+	encoded, isHex := strings.CutPrefix(key, jsonKeyPrefix)
+	if !isHex {
+		var isRaw bool
+		if encoded, isRaw = strings.CutPrefix(key, jsonRawPrefix); !isRaw {
+			return DictEntry{}, errors.New(fmt.Sprintf("Unexpected JSON key '%s', keys starting with '$' must be hex encoded", key))
+		}
+	}
+
+	decoded, err := hex.DecodeString(encoded)
+	if err != nil {
+		return DictEntry{}, errors.New(fmt.Sprintf("Cannot hex decode JSON key '%s': %s", key, err))
+	}
+
+	if isHex {
+		return DictEntry{Key: string(decoded)}, nil
+	}
+
+	val, err := Parse(decoded)
+	if err != nil || val.Kind() != String {
+		return DictEntry{}, errors.New(fmt.Sprintf("Expected JSON key '%s' to hold a bencode string", key))
+	}
+	return DictEntry{Key: string(val.Bytes()), rawKey: decoded}, nil
+}
+
+func readJSONMarker(dec *json.Decoder, marker string) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	s, ok := tok.(string)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("Expected %s to hold a hex string got %v instead", marker, tok))
+	}
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Cannot hex decode %s: %s", marker, err))
+	}
+
+	if dec.More() {
+		return nil, errors.New(fmt.Sprintf("Expected %s to be the only key of its object", marker))
+	}
+	if _, err := dec.Token(); err != nil { // the closing '}'
+		return nil, err
+	}
+
+	if marker == jsonRawKey {
+		if _, err := Parse(b); err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid bencode in %s: %s", marker, err))
+		}
+		return RawMessage(b), nil
+	}
+
+	return b, nil
+}
@@ -0,0 +1,145 @@
+package bencode
+
+import (
+	"os"
+	"testing"
+)
+
+func TestToJSON(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"i42e", "42"},
+		{"i-3e", "-3"},
+		{"4:spam", `"spam"`},
+		{"0:", `""`},
+		{"5:<a&b>", `"<a&b>"`},
+		{"le", "[]"},
+		{"li1e1:ae", `[1,"a"]`},
+		{"de", "{}"},
+		// keys keep their original order
+		{"d1:bi1e1:ai2ee", `{"b":1,"a":2}`},
+		{"2:\xff\x00", `{"$hex":"ff00"}`},
+		{"d2:\xff\x00i1ee", `{"$hex:ff00":1}`},
+		{"d4:$hexi1ee", `{"$hex:24686578":1}`},
+		{"i03e", `{"$raw":"69303365"}`},
+		{"02:hi", `{"$raw":"30323a6869"}`},
+		{"d01:ai1ee", `{"$raw:30313a61":1}`},
+	}
+
+	for _, test := range tests {
+		res, err := ToJSON([]byte(test.input))
+		if err != nil {
+			t.Fatalf("expected no error for %q got %s instead", test.input, err)
+		}
+
+		if string(res) != test.expected {
+			t.Errorf("expected %q to give %s got %s instead", test.input, test.expected, res)
+		}
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	tests := []string{
+		"i0e",
+		"i-42e",
+		"0:",
+		"le",
+		"de",
+		"d1:bi1e1:ai2ee",
+		"d1:ald1:zi1e1:yi2eeee",
+		"l2:\xff\x00d1:\x80i1eee",
+		"d4:$raw4:$hex5:$hex:i1ee",
+		"d1:ai1e1:ai2ee",
+		"d1:bi03e1:a02:hie",
+		"d01:ai1e002:$xi2ee",
+		"d4:text14:caf\xc3\xa9 \"quoted\"e",
+	}
+
+	for _, test := range tests {
+		js, err := ToJSON([]byte(test))
+		if err != nil {
+			t.Fatalf("expected no error for %q got %s instead", test, err)
+		}
+
+		res, err := FromJSON(js)
+		if err != nil {
+			t.Fatalf("expected no error for %s got %s instead", js, err)
+		}
+
+		if string(res) != test {
+			t.Errorf("expected %q to round trip through %s got %q instead", test, js, res)
+		}
+	}
+}
+
+func TestJSONRoundTripTorrent(t *testing.T) {
+	data, err := os.ReadFile("../decoder/files/test.torrent")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	js, err := ToJSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := FromJSON(js)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(res) != string(data) {
+		t.Errorf("expected test.torrent to round trip through JSON")
+	}
+}
+
+func TestFromJSON(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"b": 1, "a": [1, "x"]}`, "d1:bi1e1:ali1e1:xee"},
+		{`{"pieces": {"$hex": "0102"}}`, "d6:pieces2:\x01\x02e"},
+		{`{"$raw": "69303365"}`, "i03e"},
+		{`  "spam"  `, "4:spam"},
+	}
+
+	for _, test := range tests {
+		res, err := FromJSON([]byte(test.input))
+		if err != nil {
+			t.Fatalf("expected no error for %s got %s instead", test.input, err)
+		}
+
+		if string(res) != test.expected {
+			t.Errorf("expected %s to give %q got %q instead", test.input, test.expected, res)
+		}
+	}
+}
+
+func TestFromJSONErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"1.5",
+		"true",
+		"null",
+		"[1,",
+		"1 2",
+		`{"$foo": 1}`,
+		`{"$hex:zz": 1}`,
+		`{"$hex": "zz"}`,
+		`{"$hex": 1}`,
+		`{"$hex": "00", "a": 1}`,
+		`{"$raw": "6930"}`,
+		`{"$raw": "693165693265"}`,
+		`{"$raw:zz": 1}`,
+		`{"$raw:693165": 1}`,
+	}
+
+	for _, test := range tests {
+		if _, err := FromJSON([]byte(test)); err == nil {
+			t.Errorf("expected an error for %s", test)
+		}
+	}
+}
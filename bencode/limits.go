@@ -0,0 +1,79 @@
+package bencode
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned when decoding goes over one of the Limits,
+// use errors.Is to check which one was hit.
+var (
+	ErrMaxDepth     = errors.New("Max nesting depth exceeded")
+	ErrMaxStringLen = errors.New("Max string length exceeded")
+	ErrMaxAlloc     = errors.New("Max total allocation exceeded")
+	ErrMaxElements  = errors.New("Max list/dict element count exceeded")
+)
+
+// Limits bounds the resources used when decoding untrusted input
+// (tracker responses, KRPC packets, peer extension messages...).
+// A zero field means there is no limit.
+type Limits struct {
+	// MaxDepth is the max nesting of lists and dicts.
+	MaxDepth int
+	// MaxStringLen is the max length of a single string.
+	MaxStringLen int
+	// MaxAlloc is the max number of bytes allocated for the whole value,
+	// it counts the bytes of every string plus elementCost for each list/dict element.
+	MaxAlloc int64
+	// MaxElements is the max number of elements in a single list or dict.
+	MaxElements int
+}
+
+// DefaultLimits are used by Unmarshal and NewDecoder.
+// They only bound the nesting so a deeply nested input cannot blow up the stack,
+// use Decoder.SetLimits with tighter values for data coming from the network.
+var DefaultLimits = Limits{MaxDepth: 512}
+
+// elementCost is roughly what a list/dict element costs (an interface value),
+// it's used to account for containers when checking MaxAlloc.
+const elementCost = 16
+
+// enter is called when starting to decode a list or dict.
+func (d *decodeState) enter() error {
+	d.depth++
+	if d.limits.MaxDepth > 0 && d.depth > d.limits.MaxDepth {
+		return d.limitError(ErrMaxDepth, fmt.Sprintf("depth %d > %d", d.depth, d.limits.MaxDepth))
+	}
+	return nil
+}
+
+func (d *decodeState) leave() {
+	d.depth--
+}
+
+// addElement is called for the n-th (starting from 1) element of a list or dict.
+func (d *decodeState) addElement(n int) error {
+	if d.limits.MaxElements > 0 && n > d.limits.MaxElements {
+		return d.limitError(ErrMaxElements, fmt.Sprintf("%d elements > %d", n, d.limits.MaxElements))
+	}
+	return d.allocate(elementCost)
+}
+
+func (d *decodeState) addString(n int) error {
+	if d.limits.MaxStringLen > 0 && n > d.limits.MaxStringLen {
+		return d.limitError(ErrMaxStringLen, fmt.Sprintf("%d bytes > %d", n, d.limits.MaxStringLen))
+	}
+	return d.allocate(int64(n))
+}
+
+func (d *decodeState) allocate(n int64) error {
+	d.alloc += n
+	if d.limits.MaxAlloc > 0 && d.alloc > d.limits.MaxAlloc {
+		return d.limitError(ErrMaxAlloc, fmt.Sprintf("%d bytes > %d", d.alloc, d.limits.MaxAlloc))
+	}
+	return nil
+}
+
+func (d *decodeState) limitError(err error, details string) error {
+	return fmt.Errorf("%w (%s) at position '%d'", err, details, d.base+int64(d.pos))
+}
@@ -0,0 +1,56 @@
+package bencode
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		input    string
+		limits   Limits
+		expected error
+	}{
+		{input: "lllleeee", limits: Limits{MaxDepth: 4}, expected: nil},
+		{input: "llllleeeee", limits: Limits{MaxDepth: 4}, expected: ErrMaxDepth},
+		{input: "ld1:ald1:aleeeee", limits: Limits{MaxDepth: 4}, expected: ErrMaxDepth},
+		{input: "4:spam", limits: Limits{MaxStringLen: 4}, expected: nil},
+		{input: "5:spams", limits: Limits{MaxStringLen: 4}, expected: ErrMaxStringLen},
+		{input: "d5:spamsi1ee", limits: Limits{MaxStringLen: 4}, expected: ErrMaxStringLen},
+		{input: "li1ei2ei3ee", limits: Limits{MaxElements: 3}, expected: nil},
+		{input: "li1ei2ei3ei4ee", limits: Limits{MaxElements: 3}, expected: ErrMaxElements},
+		{input: "d1:ai1e1:bi1ee", limits: Limits{MaxElements: 1}, expected: ErrMaxElements},
+		{input: "l4:spam4:spame", limits: Limits{MaxAlloc: 8 + 2*elementCost}, expected: nil},
+		{input: "l4:spam4:spam4:spame", limits: Limits{MaxAlloc: 8 + 2*elementCost}, expected: ErrMaxAlloc},
+		// a huge declared length should fail on the limit before reading anything
+		{input: "999999999999:spam", limits: Limits{MaxStringLen: 1 << 20}, expected: ErrMaxStringLen},
+	}
+
+	for _, test := range tests {
+		var res any
+		d := decodeState{data: []byte(test.input), limits: test.limits}
+		err := d.unmarshal(&res)
+		if !errors.Is(err, test.expected) {
+			t.Errorf("input = %s expected %v got %v instead", test.input, test.expected, err)
+		}
+
+		// the stream decoder should enforce the same limits
+		dec := NewDecoder(strings.NewReader(test.input))
+		dec.SetLimits(test.limits)
+		err = dec.Decode(&res)
+		if !errors.Is(err, test.expected) {
+			t.Errorf("input = %s expected the Decoder to return %v got %v instead", test.input, test.expected, err)
+		}
+	}
+}
+
+func TestDefaultLimits(t *testing.T) {
+	// without the depth limit this would recurse a million times
+	input := strings.Repeat("l", 1_000_000) + strings.Repeat("e", 1_000_000)
+
+	var res any
+	if err := Unmarshal([]byte(input), &res); !errors.Is(err, ErrMaxDepth) {
+		t.Errorf("expected %v got %v instead", ErrMaxDepth, err)
+	}
+}
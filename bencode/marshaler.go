@@ -0,0 +1,65 @@
+package bencode
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// BencodeMarshaler is implemented by types with a custom wire form
+// (compact peer lists, concatenated piece hashes, BEP 52 file trees...).
+// MarshalBencode must return a single valid bencode value.
+type BencodeMarshaler interface {
+	MarshalBencode() ([]byte, error)
+}
+
+// BencodeUnmarshaler is implemented by types that decode themselves.
+// UnmarshalBencode receives the raw encoding of a single value,
+// it must copy the data if it wishes to keep it after returning.
+type BencodeUnmarshaler interface {
+	UnmarshalBencode(data []byte) error
+}
+
+var (
+	marshalerType   = reflect.TypeFor[BencodeMarshaler]()
+	unmarshalerType = reflect.TypeFor[BencodeUnmarshaler]()
+)
+
+// marshaler returns the BencodeMarshaler implemented by rv (or by a pointer to it).
+func marshaler(rv reflect.Value) (BencodeMarshaler, bool) {
+	if rv.Kind() != reflect.Interface && rv.Type().Implements(marshalerType) {
+		if rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return nil, false
+		}
+		return rv.Interface().(BencodeMarshaler), true
+	}
+
+	if rv.CanAddr() && reflect.PointerTo(rv.Type()).Implements(marshalerType) {
+		return rv.Addr().Interface().(BencodeMarshaler), true
+	}
+
+	return nil, false
+}
+
+func marshalCustom(w encodeWriter, m BencodeMarshaler) error {
+	b, err := m.MarshalBencode()
+	if err != nil {
+		return err
+	}
+
+	if _, err := Parse(b); err != nil {
+		return errors.New(fmt.Sprintf("%T.MarshalBencode returned an invalid value: %s", m, err))
+	}
+
+	w.Write(b)
+	return nil
+}
+
+// unmarshaler returns the BencodeUnmarshaler implemented by a pointer to rv.
+func unmarshaler(rv reflect.Value) (BencodeUnmarshaler, bool) {
+	if rv.Kind() != reflect.Pointer && rv.CanAddr() && reflect.PointerTo(rv.Type()).Implements(unmarshalerType) {
+		return rv.Addr().Interface().(BencodeUnmarshaler), true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,128 @@
+package bencode
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// NotFoundError is returned when a path points to a key or index that does not exist.
+type NotFoundError struct {
+	Path string // the path up to and including the missing part
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("Path '%s' not found", e.Path)
+}
+
+// TypeError is returned when a path goes through something that is neither a dict nor a list,
+// or when the value it points to cannot be decoded into the type asked for.
+type TypeError struct {
+	Path     string
+	Expected string
+	Got      Kind
+	Err      error // the decoding error if any
+}
+
+func (e *TypeError) Error() string {
+	msg := fmt.Sprintf("Expected %s at '%s' got %s instead", e.Expected, e.Path, e.Got)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *TypeError) Unwrap() error {
+	return e.Err
+}
+
+// Lookup follows a dot separated path such as "info.files.0.path" and returns the value it points to.
+// Each part is a key when going through a dict and an index when going through a list.
+// A dot that is part of a key can be escaped as "\.", the empty path returns v itself.
+func (v Value) Lookup(path string) (Value, error) {
+	if !v.Exists() {
+		return Value{}, &NotFoundError{Path: path}
+	}
+
+	cur := v
+	parts := splitPath(path)
+	for i, part := range parts {
+		walked := JoinPath(parts[:i+1])
+
+		switch cur.Kind() {
+		case Dict:
+			cur = cur.Get(part)
+
+		case List:
+			idx, err := strconv.Atoi(part)
+			if err != nil {
+				return Value{}, &TypeError{Path: walked, Expected: "a list index", Got: List}
+			}
+			cur = cur.Index(idx)
+
+		default:
+			return Value{}, &TypeError{Path: JoinPath(parts[:i]), Expected: "dict or list", Got: cur.Kind()}
+		}
+
+		if !cur.Exists() {
+			return Value{}, &NotFoundError{Path: walked}
+		}
+	}
+
+	return cur, nil
+}
+
+// Get looks up path in v (see Value.Lookup) and decodes what it finds into a T
+// following the same rules as Unmarshal, e.g.
+//
+//	name, err := bencode.Get[string](root, "info.name")
+//	files, err := bencode.Get[[]bencode.RawMessage](root, "info.files")
+//
+// Use errors.As with *NotFoundError and *TypeError to tell missing values from unexpected ones.
+func Get[T any](v Value, path string) (T, error) {
+	var res T
+
+	val, err := v.Lookup(path)
+	if err != nil {
+		return res, err
+	}
+
+	if err := val.Decode(&res); err != nil {
+		return res, &TypeError{Path: path, Expected: reflect.TypeFor[T]().String(), Got: val.Kind(), Err: err}
+	}
+
+	return res, nil
+}
+
+func splitPath(path string) []string {
+	if path == "" {
+		return nil
+	}
+
+	parts := make([]string, 0)
+	var part strings.Builder
+	for i := 0; i < len(path); i++ {
+		switch {
+		case path[i] == '\\' && i+1 < len(path) && path[i+1] == '.':
+			part.WriteByte('.')
+			i++
+		case path[i] == '.':
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteByte(path[i])
+		}
+	}
+
+	return append(parts, part.String())
+}
+
+// JoinPath builds a path for Lookup out of keys & indexes, dots in keys are escaped.
+func JoinPath(parts []string) string {
+	escaped := make([]string, len(parts))
+	for i, part := range parts {
+		escaped[i] = strings.ReplaceAll(part, ".", `\.`)
+	}
+	return strings.Join(escaped, ".")
+}
@@ -0,0 +1,120 @@
+package bencode
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const pathTestDoc = "d8:announce3:url4:infod5:filesld6:lengthi10e4:pathl1:a5:b.txteed6:lengthi20e4:pathl5:c.txteee4:name3:dire7:a.b.keyi1ee"
+
+func TestLookup(t *testing.T) {
+	root, err := Parse([]byte(pathTestDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"", pathTestDoc},
+		{"announce", "3:url"},
+		{"info.name", "3:dir"},
+		{"info.files.1.length", "i20e"},
+		{"info.files.0.path.1", "5:b.txt"},
+		{`a\.b\.key`, "i1e"},
+	}
+
+	for _, test := range tests {
+		val, err := root.Lookup(test.path)
+		if err != nil {
+			t.Fatalf("expected no error for %s got %s instead", test.path, err)
+		}
+
+		if string(val.Raw()) != test.expected {
+			t.Errorf("expected %s to give %s got %s instead", test.path, test.expected, val.Raw())
+		}
+	}
+}
+
+func TestLookupErrors(t *testing.T) {
+	root, err := Parse([]byte(pathTestDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path     string
+		notFound bool
+		errPath  string
+	}{
+		{"comment", true, "comment"},
+		{"info.files.2.length", true, "info.files.2"},
+		{"info.files.-1", true, "info.files.-1"},
+		{"info.files.first", false, "info.files.first"},
+		{"announce.foo", false, "announce"},
+		{"info.files.0.length.x", false, "info.files.0.length"},
+		{"a.b.key", true, "a"},
+	}
+
+	for _, test := range tests {
+		_, err := root.Lookup(test.path)
+
+		var notFound *NotFoundError
+		var typeErr *TypeError
+		switch {
+		case test.notFound && errors.As(err, &notFound):
+			if notFound.Path != test.errPath {
+				t.Errorf("expected %s to fail at %s got %s instead", test.path, test.errPath, notFound.Path)
+			}
+		case !test.notFound && errors.As(err, &typeErr):
+			if typeErr.Path != test.errPath {
+				t.Errorf("expected %s to fail at %s got %s instead", test.path, test.errPath, typeErr.Path)
+			}
+		default:
+			t.Errorf("unexpected error for %s: %v", test.path, err)
+		}
+	}
+}
+
+func TestGet(t *testing.T) {
+	root, err := Parse([]byte(pathTestDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name, err := Get[string](root, "info.name")
+	if err != nil || name != "dir" {
+		t.Errorf("expected dir got %s (%v) instead", name, err)
+	}
+
+	length, err := Get[int](root, "info.files.1.length")
+	if err != nil || length != 20 {
+		t.Errorf("expected 20 got %d (%v) instead", length, err)
+	}
+
+	path, err := Get[[]string](root, "info.files.0.path")
+	if err != nil || !reflect.DeepEqual(path, []string{"a", "b.txt"}) {
+		t.Errorf("expected [a b.txt] got %v (%v) instead", path, err)
+	}
+
+	file, err := Get[map[string]any](root, "info.files.1")
+	if err != nil || !reflect.DeepEqual(file, map[string]any{"length": int64(20), "path": []any{"c.txt"}}) {
+		t.Errorf("unexpected file %v (%v)", file, err)
+	}
+
+	var typeErr *TypeError
+	if _, err := Get[int](root, "info.name"); !errors.As(err, &typeErr) || typeErr.Expected != "int" || typeErr.Got != String {
+		t.Errorf("expected a type error got %v instead", err)
+	}
+
+	var notFound *NotFoundError
+	if _, err := Get[int](root, "info.pieces"); !errors.As(err, &notFound) {
+		t.Errorf("expected a not found error got %v instead", err)
+	}
+
+	if _, err := Get[int](Value{}, ""); !errors.As(err, &notFound) {
+		t.Errorf("expected a not found error for an invalid value got %v instead", err)
+	}
+}
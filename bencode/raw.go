@@ -0,0 +1,27 @@
+package bencode
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// RawMessage is a raw encoded bencode value.
+// Unmarshal stores the exact bytes of the value in it and Marshal writes them back untouched,
+// this is what lets us hash the "info" dict exactly as it appears in a .torrent file.
+type RawMessage []byte
+
+var rawMessageType = reflect.TypeFor[RawMessage]()
+
+func marshalRaw(w encodeWriter, raw RawMessage) error {
+	if len(raw) == 0 {
+		return errors.New("Cannot encode an empty RawMessage")
+	}
+
+	if _, err := Parse(raw); err != nil {
+		return errors.New(fmt.Sprintf("RawMessage does not hold a single valid value: %s", err))
+	}
+
+	w.Write(raw)
+	return nil
+}
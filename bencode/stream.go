@@ -0,0 +1,218 @@
+package bencode
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// A Decoder reads and decodes bencode values from an input stream.
+//
+// Values can be decoded back-to-back, and since peer extension messages & KRPC
+// packets carry a raw payload right after the bencoded part, the Decoder keeps track
+// of how many bytes it consumed (see InputOffset & Buffered).
+type Decoder struct {
+	r       *bufio.Reader
+	offset  int64
+	buf     []byte
+	strict  bool
+	limits  Limits
+	ordered bool
+	bigInts bool
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r), limits: DefaultLimits}
+}
+
+// Decode reads the next bencode value from the input and stores it in the value pointed to by v
+// following the same rules as Unmarshal.
+// It returns io.EOF when the input ends right before a value and a *SyntaxError
+// wrapping io.ErrUnexpectedEOF when it ends in the middle of one.
+func (d *Decoder) Decode(v any) error {
+	start := d.offset
+	raw, err := d.readValue()
+	if err != nil {
+		return err
+	}
+
+	state := decodeState{data: raw, strict: d.strict, base: start, limits: d.limits, ordered: d.ordered, bigInts: d.bigInts}
+	return state.unmarshal(v)
+}
+
+// SetLimits bounds the resources used to decode each value, see Limits.
+// Limits are checked while reading from the input so a malicious value is rejected
+// before it is fully buffered.
+func (d *Decoder) SetLimits(limits Limits) {
+	d.limits = limits
+}
+
+// UseOrderedDict makes the Decoder decode dicts into *OrderedDict instead of map[string]any
+// when decoding into an empty interface, so the value can be encoded back byte-for-byte.
+func (d *Decoder) UseOrderedDict() {
+	d.ordered = true
+}
+
+// UseBigInt makes the Decoder decode ints into *big.Int instead of int64 when decoding into
+// an empty interface, so ints of any size can be decoded.
+func (d *Decoder) UseBigInt() {
+	d.bigInts = true
+}
+
+// SetStrict makes the Decoder reject any non canonical encoding (see UnmarshalStrict).
+func (d *Decoder) SetStrict(strict bool) {
+	d.strict = strict
+}
+
+// InputOffset returns the number of bytes consumed from the input so far,
+// that is the position right after the last decoded value.
+func (d *Decoder) InputOffset() int64 {
+	return d.offset
+}
+
+// Buffered returns a reader of the data that was read from the input but not decoded yet.
+// The reader is only valid until the next call to Decode.
+func (d *Decoder) Buffered() io.Reader {
+	b, _ := d.r.Peek(d.r.Buffered())
+	return bytes.NewReader(b)
+}
+
+// readValue reads exactly one bencode value from the input without decoding it.
+// Nesting is tracked with a counter instead of recursion.
+func (d *Decoder) readValue() ([]byte, error) {
+	d.buf = d.buf[:0]
+	depth := 0
+	for {
+		c, err := d.r.ReadByte()
+		if err != nil {
+			if err == io.EOF && len(d.buf) != 0 {
+				return nil, d.unexpectedEOF("a value or 'e'")
+			}
+			return nil, err
+		}
+		d.buf = append(d.buf, c)
+		// the raw value is buffered before being decoded so bound that as well
+		if d.limits.MaxAlloc > 0 && int64(len(d.buf)) > d.limits.MaxAlloc {
+			return nil, d.limitError(ErrMaxAlloc, fmt.Sprintf("%d bytes > %d", len(d.buf), d.limits.MaxAlloc))
+		}
+
+		switch {
+		case c == 'i':
+			if err := d.readUntil('e'); err != nil {
+				return nil, err
+			}
+
+		case c == 'l' || c == 'd':
+			depth++
+			if d.limits.MaxDepth > 0 && depth > d.limits.MaxDepth {
+				return nil, d.limitError(ErrMaxDepth, fmt.Sprintf("depth %d > %d", depth, d.limits.MaxDepth))
+			}
+
+		case c == 'e':
+			if depth == 0 {
+				return nil, d.syntaxError("a value")
+			}
+			depth--
+
+		case isDigit(c):
+			start := len(d.buf) - 1
+			if err := d.readUntil(':'); err != nil {
+				return nil, err
+			}
+
+			digits := d.buf[start : len(d.buf)-1]
+			for i, c := range digits {
+				if !isDigit(c) {
+					return nil, &SyntaxError{Offset: d.offset + int64(start+i), Expected: "':'", Got: strconv.QuoteRune(rune(c))}
+				}
+			}
+
+			// same error as Unmarshal for a length that doesn't fit in an int
+			strLen, err := strconv.Atoi(string(digits))
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("Cannot convert string length at %d got '%s' instead", d.offset+int64(start), digits))
+			}
+
+			if d.limits.MaxStringLen > 0 && strLen > d.limits.MaxStringLen {
+				return nil, d.limitError(ErrMaxStringLen, fmt.Sprintf("%d bytes > %d", strLen, d.limits.MaxStringLen))
+			}
+			if d.limits.MaxAlloc > 0 && int64(len(d.buf)+strLen) > d.limits.MaxAlloc {
+				return nil, d.limitError(ErrMaxAlloc, fmt.Sprintf("%d bytes > %d", len(d.buf)+strLen, d.limits.MaxAlloc))
+			}
+
+			if err := d.readN(strLen); err != nil {
+				return nil, err
+			}
+
+		default:
+			return nil, d.syntaxError("a value")
+		}
+
+		if depth == 0 {
+			d.offset += int64(len(d.buf))
+			return d.buf, nil
+		}
+	}
+}
+
+func (d *Decoder) limitError(err error, details string) error {
+	return fmt.Errorf("%w (%s) at position '%d'", err, details, d.offset+int64(len(d.buf))-1)
+}
+
+// syntaxError reports that the last byte read is not what was "expected".
+func (d *Decoder) syntaxError(expected string) error {
+	last := d.buf[len(d.buf)-1]
+	return &SyntaxError{
+		Offset:   d.offset + int64(len(d.buf)) - 1,
+		Expected: expected,
+		Got:      strconv.QuoteRune(rune(last)),
+	}
+}
+
+func (d *Decoder) unexpectedEOF(expected string) error {
+	return &SyntaxError{Offset: d.offset + int64(len(d.buf)), Expected: expected, Got: gotEOF}
+}
+
+func (d *Decoder) readUntil(delim byte) error {
+	for {
+		b, err := d.r.ReadSlice(delim)
+		d.buf = append(d.buf, b...)
+		// ReadSlice fails with ErrBufferFull when delim is not in the buffer, ints can have
+		// any number of digits (see UseBigInt) so keep reading instead of failing
+		if err == bufio.ErrBufferFull {
+			if d.limits.MaxAlloc > 0 && int64(len(d.buf)) > d.limits.MaxAlloc {
+				return d.limitError(ErrMaxAlloc, fmt.Sprintf("%d bytes > %d", len(d.buf), d.limits.MaxAlloc))
+			}
+			continue
+		}
+		if err == io.EOF {
+			return d.unexpectedEOF(strconv.QuoteRune(rune(delim)))
+		}
+		return err
+	}
+}
+
+// readN reads the n bytes of a string, it grows the buffer chunk by chunk
+// so a bogus length does not allocate more than what the input actually holds.
+func (d *Decoder) readN(n int) error {
+	const chunkSize = 64 * 1024
+	for n > 0 {
+		chunk := min(n, chunkSize)
+		start := len(d.buf)
+		d.buf = append(d.buf, make([]byte, chunk)...)
+		read, err := io.ReadFull(d.r, d.buf[start:])
+		if err != nil {
+			d.buf = d.buf[:start+read]
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return d.unexpectedEOF(fmt.Sprintf("%d more bytes of string", n-read))
+			}
+			return err
+		}
+		n -= chunk
+	}
+
+	return nil
+}
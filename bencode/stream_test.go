@@ -0,0 +1,153 @@
+package bencode
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecoder(t *testing.T) {
+	input := "i42e4:spamli1e1:aed1:kl1:veeRAW PAYLOAD"
+	dec := NewDecoder(strings.NewReader(input))
+
+	tests := []struct {
+		expected any
+		offset   int64
+	}{
+		{expected: int64(42), offset: 4},
+		{expected: "spam", offset: 10},
+		{expected: []any{int64(1), "a"}, offset: 18},
+		{expected: map[string]any{"k": []any{"v"}}, offset: 28},
+	}
+
+	for _, test := range tests {
+		var res any
+		if err := dec.Decode(&res); err != nil {
+			t.Fatalf("expected no error got %s instead", err)
+		}
+
+		if !reflect.DeepEqual(res, test.expected) {
+			t.Errorf("expected %+v got %+v", test.expected, res)
+		}
+
+		if dec.InputOffset() != test.offset {
+			t.Errorf("expected offset %d got %d instead", test.offset, dec.InputOffset())
+		}
+	}
+
+	rest, err := io.ReadAll(dec.Buffered())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rest) != "RAW PAYLOAD" {
+		t.Errorf("expected the raw payload to be left got %s instead", rest)
+	}
+}
+
+func TestDecoderEOF(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected error
+	}{
+		{input: "", expected: io.EOF},
+		{input: "i42", expected: io.ErrUnexpectedEOF},
+		{input: "4:sp", expected: io.ErrUnexpectedEOF},
+		{input: "ld1:ki1e", expected: io.ErrUnexpectedEOF},
+	}
+
+	for _, test := range tests {
+		var res any
+		err := NewDecoder(strings.NewReader(test.input)).Decode(&res)
+		if !errors.Is(err, test.expected) {
+			t.Errorf("input = %s expected %s got %v instead", test.input, test.expected, err)
+		}
+	}
+}
+
+func TestDecoderErrors(t *testing.T) {
+	tests := []string{
+		"e",
+		"x",
+		"-1:a",
+		"li1ex",
+	}
+
+	for _, test := range tests {
+		var res any
+		if err := NewDecoder(strings.NewReader(test)).Decode(&res); err == nil {
+			t.Errorf("expected an error for input %s", test)
+		}
+	}
+}
+
+func TestDecoderSameErrors(t *testing.T) {
+	digits := strings.Repeat("1", 5000)
+	tests := []string{
+		// longer than the bufio buffer
+		"i" + digits + "e",
+		digits + ":a",
+		"i1x2e",
+		"12x:ab",
+		"i--1e",
+	}
+
+	for _, test := range tests {
+		var expected, res int64
+		expectedErr := Unmarshal([]byte(test), &expected)
+		err := NewDecoder(strings.NewReader(test)).Decode(&res)
+
+		if expectedErr == nil || err == nil || reflect.TypeOf(err) != reflect.TypeOf(expectedErr) || err.Error() != expectedErr.Error() {
+			t.Errorf("expected %q to fail with %T %v got %T %v instead", test[:min(len(test), 20)], expectedErr, expectedErr, err, err)
+		}
+	}
+
+	// big ints of any size work with UseBigInt
+	dec := NewDecoder(strings.NewReader("i" + digits + "e"))
+	dec.UseBigInt()
+	var res any
+	if err := dec.Decode(&res); err != nil {
+		t.Errorf("expected no error got %s instead", err)
+	}
+}
+
+func TestDecoderStruct(t *testing.T) {
+	var res struct {
+		T string `bencode:"t"`
+		Y string `bencode:"y"`
+		A struct {
+			ID []byte `bencode:"id"`
+		} `bencode:"a"`
+	}
+
+	dec := NewDecoder(strings.NewReader("d1:ad2:id3:abce1:t2:aa1:y1:qe"))
+	if err := dec.Decode(&res); err != nil {
+		t.Fatalf("expected no error got %s instead", err)
+	}
+
+	if res.T != "aa" || res.Y != "q" || string(res.A.ID) != "abc" {
+		t.Errorf("unexpected result %+v", res)
+	}
+}
+
+func TestDecoderStrict(t *testing.T) {
+	dec := NewDecoder(strings.NewReader("i1ed1:bi1e1:ai2ee"))
+	dec.SetStrict(true)
+
+	var res any
+	if err := dec.Decode(&res); err != nil {
+		t.Fatalf("expected no error got %s instead", err)
+	}
+
+	var syntaxErr *SyntaxError
+	err := dec.Decode(&res)
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("expected a *SyntaxError got %v instead", err)
+	}
+
+	// offset should be relative to the whole stream and not to the value
+	if syntaxErr.Offset != 10 {
+		t.Errorf("expected error at offset 10 got %d instead", syntaxErr.Offset)
+	}
+}
@@ -0,0 +1,296 @@
+package bencode
+
+import (
+	"bytes"
+	"errors"
+)
+
+// Kind is the type of a bencode value.
+type Kind int
+
+const (
+	Invalid Kind = iota
+	Int
+	String
+	List
+	Dict
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Int:
+		return "int"
+	case String:
+		return "string"
+	case List:
+		return "list"
+	case Dict:
+		return "dict"
+	default:
+		return "invalid"
+	}
+}
+
+// Value is a lightweight view of a bencode value inside a buffer returned by Parse.
+// Values never copy the buffer, Bytes/Raw/Get/Index all slice into it,
+// so large strings such as "pieces" are not allocated until they are actually needed.
+//
+// The zero Value (and the result of looking up a missing key or index) has Kind Invalid.
+type Value struct {
+	buf        []byte
+	start, end int
+}
+
+// Parse validates data as a single bencode value and returns a view of it.
+// The whole input is checked once, with the same (lenient) rules and limits as Unmarshal,
+// after which walking the Value cannot fail on a syntax error.
+func Parse(data []byte) (Value, error) {
+	d := decodeState{data: data, limits: DefaultLimits}
+	return d.parse()
+}
+
+// ParseStrict is like Parse but only accepts canonical bencode (see UnmarshalStrict).
+func ParseStrict(data []byte) (Value, error) {
+	d := decodeState{data: data, strict: true, limits: DefaultLimits}
+	return d.parse()
+}
+
+func (v Value) Kind() Kind {
+	if v.buf == nil || v.start >= v.end {
+		return Invalid
+	}
+
+	switch c := v.buf[v.start]; {
+	case c == 'i':
+		return Int
+	case c == 'l':
+		return List
+	case c == 'd':
+		return Dict
+	default:
+		return String
+	}
+}
+
+// Exists reports whether v holds a value, it's false for missing keys and indexes.
+func (v Value) Exists() bool {
+	return v.Kind() != Invalid
+}
+
+// Raw returns the exact encoding of v.
+func (v Value) Raw() []byte {
+	if !v.Exists() {
+		return nil
+	}
+	return v.buf[v.start:v.end]
+}
+
+// Offset returns the position of v in the buffer given to Parse.
+func (v Value) Offset() int {
+	return v.start
+}
+
+// Bytes returns the content of a string value, or nil if v is not a string.
+func (v Value) Bytes() []byte {
+	if v.Kind() != String {
+		return nil
+	}
+
+	colon := v.start + bytes.IndexByte(v.buf[v.start:v.end], ':')
+	return v.buf[colon+1 : v.end]
+}
+
+// Len returns the number of elements of a list or dict and 0 for anything else.
+func (v Value) Len() int {
+	n := 0
+	v.Range(func(_ []byte, _ Value) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// Index returns the i-th element of a list.
+func (v Value) Index(i int) Value {
+	if v.Kind() != List || i < 0 {
+		return Value{}
+	}
+
+	var res Value
+	v.Range(func(_ []byte, elem Value) bool {
+		if i == 0 {
+			res = elem
+			return false
+		}
+		i--
+		return true
+	})
+	return res
+}
+
+// Get returns the value of key in a dict.
+// When the key is present more than once (only possible with lenient parsing) the last one wins,
+// just like when decoding into a map.
+func (v Value) Get(key string) Value {
+	if v.Kind() != Dict {
+		return Value{}
+	}
+
+	var res Value
+	v.Range(func(k []byte, elem Value) bool {
+		if string(k) == key {
+			res = elem
+		}
+		return true
+	})
+	return res
+}
+
+// Range calls fn for each element of a list (with a nil key) or each entry of a dict
+// in the order they appear in the buffer, it stops as soon as fn returns false.
+func (v Value) Range(fn func(key []byte, elem Value) bool) {
+	v.rangeEntries(func(key, elem Value) bool {
+		var k []byte
+		if key.Exists() {
+			k = key.Bytes()
+		}
+		return fn(k, elem)
+	})
+}
+
+// rangeEntries is Range giving the key as a Value (invalid for lists) so its raw bytes can be kept.
+func (v Value) rangeEntries(fn func(key, elem Value) bool) {
+	kind := v.Kind()
+	if kind != List && kind != Dict {
+		return
+	}
+
+	pos := v.start + 1
+	for v.buf[pos] != 'e' {
+		var key Value
+		if kind == Dict {
+			keyEnd := skipValue(v.buf, pos)
+			key = Value{buf: v.buf, start: pos, end: keyEnd}
+			pos = keyEnd
+		}
+
+		end := skipValue(v.buf, pos)
+		if !fn(key, Value{buf: v.buf, start: pos, end: end}) {
+			return
+		}
+		pos = end
+	}
+}
+
+// Interface materializes v into the generic representation used by Unmarshal
+// when decoding into an empty interface: map[string]any, []any, string and int64.
+func (v Value) Interface() (any, error) {
+	d := decodeState{data: v.buf}
+	return d.materialize(v)
+}
+
+// materialize is Value.Interface taking the decoding options into account.
+func (d *decodeState) materialize(val Value) (any, error) {
+	if d.ordered && !val.canonical() {
+		return RawMessage(bytes.Clone(val.Raw())), nil
+	}
+
+	switch val.Kind() {
+	case Int:
+		return d.interfaceInt(val)
+
+	case String:
+		return string(val.Bytes()), nil
+
+	case List:
+		arr := make([]any, 0)
+		var err error
+		val.Range(func(_ []byte, elem Value) bool {
+			var v any
+			v, err = d.materialize(elem)
+			arr = append(arr, v)
+			return err == nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return arr, nil
+
+	case Dict:
+		if d.ordered {
+			return d.materializeOrdered(val)
+		}
+
+		dict := make(map[string]any)
+		var err error
+		val.Range(func(key []byte, elem Value) bool {
+			var v any
+			v, err = d.materialize(elem)
+			dict[string(key)] = v
+			return err == nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return dict, nil
+	}
+
+	return nil, errors.New("Cannot materialize an invalid value")
+}
+
+// Decode stores v in the value pointed to by target following the same rules as Unmarshal.
+func (v Value) Decode(target any) error {
+	if !v.Exists() {
+		return errors.New("Cannot decode an invalid value")
+	}
+
+	d := decodeState{data: v.buf}
+	return d.unmarshalInto(v, target)
+}
+
+// canonical reports whether an int or string is written the way Marshal would write it,
+// that is without leading zeros or "-0".
+func (v Value) canonical() bool {
+	switch v.Kind() {
+	case Int:
+		digits := v.buf[v.start+1 : v.end-1]
+		if digits[0] == '-' {
+			return digits[1] != '0'
+		}
+		return digits[0] != '0' || len(digits) == 1
+
+	case String:
+		return v.buf[v.start] != '0' || v.buf[v.start+1] == ':'
+	}
+
+	return true
+}
+
+// skipValue returns the position right after the value starting at pos.
+// buf must have been validated beforehand (see Parse) since nothing is checked here.
+// Nesting is tracked with a counter so this does not recurse.
+func skipValue(buf []byte, pos int) int {
+	depth := 0
+	for {
+		switch c := buf[pos]; {
+		case c == 'i':
+			pos += bytes.IndexByte(buf[pos:], 'e') + 1
+		case c == 'l' || c == 'd':
+			depth++
+			pos++
+		case c == 'e':
+			depth--
+			pos++
+		default:
+			strLen := 0
+			for ; buf[pos] != ':'; pos++ {
+				strLen = strLen*10 + int(buf[pos]-'0')
+			}
+			pos += strLen + 1
+		}
+
+		if depth == 0 {
+			return pos
+		}
+	}
+}
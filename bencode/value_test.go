@@ -0,0 +1,149 @@
+package bencode
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	input := []byte("d4:infod6:lengthi10e4:name4:test5:filesld4:pathl1:a1:beeee8:announce3:urle")
+
+	root, err := Parse(input)
+	if err != nil {
+		t.Fatalf("expected no error got %s instead", err)
+	}
+
+	if root.Kind() != Dict || root.Len() != 2 {
+		t.Fatalf("expected a dict of 2 keys got %s of %d", root.Kind(), root.Len())
+	}
+
+	info := root.Get("info")
+	if info.Kind() != Dict {
+		t.Fatalf("expected info to be a dict got %s instead", info.Kind())
+	}
+
+	if string(info.Raw()) != "d6:lengthi10e4:name4:test5:filesld4:pathl1:a1:beeee" {
+		t.Errorf("unexpected raw info %s", info.Raw())
+	}
+
+	length, err := info.Get("length").Int()
+	if err != nil || length != 10 {
+		t.Errorf("expected length to be 10 got %d (%v)", length, err)
+	}
+
+	name := info.Get("name").Bytes()
+	if string(name) != "test" {
+		t.Errorf("expected name to be test got %s instead", name)
+	}
+
+	// views should point into the input and not to a copy of it
+	if &name[0] != &input[bytes.Index(input, []byte("test"))] {
+		t.Errorf("expected Bytes to slice into the input")
+	}
+
+	path := info.Get("files").Index(0).Get("path")
+	if path.Len() != 2 || string(path.Index(1).Bytes()) != "b" {
+		t.Errorf("unexpected path %s", path.Raw())
+	}
+
+	// missing keys, indexes and wrong kinds should return an invalid value
+	missing := []Value{
+		root.Get("nope"),
+		info.Get("files").Index(1),
+		info.Get("files").Index(-1),
+		info.Get("length").Get("x"),
+		root.Get("announce").Index(0),
+		root.Get("nope").Get("nope"),
+	}
+	for _, v := range missing {
+		if v.Exists() || v.Kind() != Invalid || v.Raw() != nil {
+			t.Errorf("expected an invalid value got %s instead", v.Raw())
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"d1:a",
+		"i1ei2e",
+		"5:ab",
+		"l1:ax",
+	}
+
+	for _, test := range tests {
+		if _, err := Parse([]byte(test)); err == nil {
+			t.Errorf("expected an error for input %s", test)
+		}
+	}
+
+	if _, err := ParseStrict([]byte("d1:bi1e1:ai1ee")); err == nil {
+		t.Errorf("expected strict parsing to reject unsorted keys")
+	}
+}
+
+func TestValueInterface(t *testing.T) {
+	root, err := Parse([]byte("d1:ali1e1:be1:bd1:ci-3eee"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := root.Interface()
+	if err != nil {
+		t.Fatalf("expected no error got %s instead", err)
+	}
+
+	expected := map[string]any{"a": []any{int64(1), "b"}, "b": map[string]any{"c": int64(-3)}}
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("expected %+v got %+v", expected, res)
+	}
+
+	var target struct {
+		C int `bencode:"c"`
+	}
+	if err := root.Get("b").Decode(&target); err != nil || target.C != -3 {
+		t.Errorf("expected to decode c=-3 got %+v (%v)", target, err)
+	}
+}
+
+// buildTorrent returns a single file torrent whose "pieces" is size bytes long.
+func buildTorrent(size int) []byte {
+	pieces := strings.Repeat("x", size)
+	return []byte(fmt.Sprintf("d8:announce15:http://tracker/4:infod6:lengthi%de4:name4:test12:piece lengthi262144e6:pieces%d:%see",
+		size/20*262144, len(pieces), pieces))
+}
+
+func BenchmarkParseLargeTorrent(b *testing.B) {
+	data := buildTorrent(50 << 20)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for range b.N {
+		root, err := Parse(data)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if len(root.Get("info").Get("pieces").Bytes()) != 50<<20 {
+			b.Fatal("unexpected pieces length")
+		}
+	}
+}
+
+func BenchmarkUnmarshalLargeTorrent(b *testing.B) {
+	data := buildTorrent(50 << 20)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for range b.N {
+		var res any
+		if err := Unmarshal(data, &res); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
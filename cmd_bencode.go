@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"gotorrent/bencode"
+	"gotorrent/decoder"
+	"io"
+	"os"
+)
+
+const bencodeUsage = `usage: gotorrent bencode <command> [arguments]
+
+commands:
+  to-json [-pretty] [file]   convert a bencode document to JSON
+  from-json [file]           convert JSON produced by to-json back to bencode
+  get [-r] <path> [file]     print the value at path (e.g. info.files.0.path) as JSON,
+                             -r prints strings as is instead
+  diff <a> <b>               show what was added (+), removed (-) or changed (~) from a to b
+                             and whether the info hash changed when both are torrents
+
+file defaults to stdin ('-' works as well), output is written to stdout.
+`
+
+func runBencode(args []string) error {
+	if len(args) == 0 {
+		return usageError{bencodeUsage}
+	}
+
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		fmt.Print(bencodeUsage)
+		return nil
+	case "to-json":
+		return runToJSON(args[1:])
+	case "from-json":
+		return runFromJSON(args[1:])
+	case "get":
+		return runGet(args[1:])
+	case "diff":
+		return runDiff(args[1:])
+	}
+
+	return usageError{fmt.Sprintf("Unknown bencode command '%s'\n\n%s", args[0], bencodeUsage)}
+}
+
+func runToJSON(args []string) error {
+	flags := flag.NewFlagSet("to-json", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: gotorrent bencode to-json [-pretty] [file]")
+		fmt.Fprintln(flags.Output(), "converts a bencode document to JSON")
+		flags.PrintDefaults()
+	}
+	pretty := flags.Bool("pretty", false, "indent the JSON output")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	data, err := readInput(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	res, err := bencode.ToJSON(data)
+	if err != nil {
+		return err
+	}
+
+	if *pretty {
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, res, "", "  "); err != nil {
+			return err
+		}
+		res = buf.Bytes()
+	}
+
+	_, err = fmt.Printf("%s\n", res)
+	return err
+}
+
+func runFromJSON(args []string) error {
+	flags := flag.NewFlagSet("from-json", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: gotorrent bencode from-json [file]")
+		fmt.Fprintln(flags.Output(), "converts JSON produced by to-json back to bencode")
+	}
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	data, err := readInput(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	res, err := bencode.FromJSON(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stdout.Write(res)
+	return err
+}
+
+func runGet(args []string) error {
+	flags := flag.NewFlagSet("get", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: gotorrent bencode get [-r] <path> [file]")
+		fmt.Fprintln(flags.Output(), "prints the value at path (e.g. info.files.0.path) as JSON")
+		flags.PrintDefaults()
+	}
+	raw := flags.Bool("r", false, "print strings as is instead of as JSON")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		return usageError{"Missing path\n\n" + bencodeUsage}
+	}
+
+	data, err := readInput(flags.Args()[1:])
+	if err != nil {
+		return err
+	}
+
+	root, err := bencode.Parse(data)
+	if err != nil {
+		return err
+	}
+
+	val, err := root.Lookup(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if *raw && val.Kind() == bencode.String {
+		_, err = os.Stdout.Write(val.Bytes())
+		return err
+	}
+
+	res, err := bencode.ToJSON(val.Raw())
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Printf("%s\n", res)
+	return err
+}
+
+func runDiff(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: gotorrent bencode diff <a> <b>")
+		fmt.Fprintln(flags.Output(), "shows what was added (+), removed (-) or changed (~) from a to b")
+		fmt.Fprintln(flags.Output(), "and whether the info hash changed when both are torrents")
+	}
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	args = flags.Args()
+	if len(args) != 2 {
+		flags.Usage()
+		return usageError{"Expected two files to compare"}
+	}
+
+	a, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	b, err := os.ReadFile(args[1])
+	if err != nil {
+		return err
+	}
+
+	oldRoot, err := bencode.Parse(a)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Cannot parse %s: %s", args[0], err))
+	}
+	newRoot, err := bencode.Parse(b)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Cannot parse %s: %s", args[1], err))
+	}
+
+	// the info hash only makes sense when comparing two torrents, anything else
+	// (e.g. tracker responses) gets a plain diff
+	diff, err := decoder.DiffTorrentFiles(a, b)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Not comparing info hashes: %s\n", err)
+		diff = &decoder.TorrentDiff{Changes: bencode.Diff(oldRoot, newRoot)}
+	}
+
+	for _, change := range diff.Changes {
+		fmt.Println(change)
+	}
+
+	if err == nil {
+		if diff.InfoHashChanged() {
+			fmt.Printf("info hash changed: %x -> %x\n", diff.OldInfoHash, diff.NewInfoHash)
+		} else {
+			fmt.Printf("info hash unchanged: %x\n", diff.OldInfoHash)
+		}
+	}
+
+	return nil
+}
+
+// readInput reads the file given as the only argument or stdin when there's none or it's "-".
+func readInput(args []string) ([]byte, error) {
+	if len(args) > 1 {
+		return nil, usageError{fmt.Sprintf("Expected at most one file got %d instead", len(args))}
+	}
+
+	if len(args) == 0 || args[0] == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+
+	return os.ReadFile(args[0])
+}
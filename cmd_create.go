@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"gotorrent/creator"
+	"gotorrent/encoder"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// stringsFlag is a flag that can be given more than once.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsFlag) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+func runCreate(args []string) error {
+	flags := flag.NewFlagSet("create", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: gotorrent create [flags] <file or directory>")
+		flags.PrintDefaults()
+	}
+
+	var trackers, webSeeds, ignore stringsFlag
+	flags.Var(&trackers, "a", "tracker url, can be repeated to add tiers, separate urls with ',' to put them in the same tier")
+	flags.Var(&webSeeds, "w", "web seed url, can be repeated")
+	flags.Var(&ignore, "ignore", "glob pattern of files to skip (e.g. '*.tmp' or '.git'), can be repeated")
+	output := flags.String("o", "", "output file (defaults to <name>.torrent)")
+	name := flags.String("name", "", "torrent name (defaults to the base name of the input)")
+	pieceLength := flags.Int("piece-length", 0, "piece length in bytes (picked automatically by default)")
+	comment := flags.String("comment", "", "comment")
+	createdBy := flags.String("created-by", "gotorrent", "created by")
+	noDate := flags.Bool("no-date", false, "don't write the creation date")
+	private := flags.Bool("private", false, "set the private flag")
+	source := flags.String("source", "", "source, private trackers use it to get a different info hash")
+	workers := flags.Int("workers", 0, "number of pieces hashed in parallel (defaults to the number of CPUs)")
+	hybrid := flags.Bool("hybrid", false, "create a hybrid v1 + v2 torrent (files are padded to piece boundaries)")
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return usageError{"Expected a single file or directory"}
+	}
+
+	opts := creator.Options{
+		Name:        *name,
+		PieceLength: *pieceLength,
+		Comment:     *comment,
+		CreatedBy:   *createdBy,
+		Private:     *private,
+		WebSeeds:    webSeeds,
+		Source:      *source,
+		Ignore:      ignore,
+		Workers:     *workers,
+		Hybrid:      *hybrid,
+	}
+	if len(trackers) > 0 {
+		opts.AnnounceList = splitTiers(trackers)
+	}
+	if !*noDate {
+		opts.CreationDate = time.Now()
+	}
+
+	torrent, err := creator.Create(flags.Arg(0), opts)
+	if err != nil {
+		return err
+	}
+
+	encoded, err := encoder.Encode(torrent)
+	if err != nil {
+		return err
+	}
+
+	out := *output
+	if out == "" {
+		out = filepath.Base(torrent.Info.Name) + ".torrent"
+	}
+	if err := os.WriteFile(out, []byte(encoded), 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("Created %s (%d files, %d pieces of %d bytes)\n", out, len(torrent.Info.FileEntries()), torrent.Info.NumPieces(), torrent.Info.PieceLength)
+	fmt.Printf("info hash: %x\n", torrent.InfoHash())
+	if torrent.Info.HasV2() {
+		fmt.Printf("v2 info hash: %x\n", torrent.InfoHashV2())
+	}
+	return nil
+}
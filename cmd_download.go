@@ -0,0 +1,32 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+)
+
+// @TODO: implement downloading & seeding, the tracker client is the only piece of the protocol we have so far
+
+func runDownload(args []string) error {
+	return notImplemented("download", "usage: gotorrent download <file.torrent | magnet link>", args)
+}
+
+func runSeed(args []string) error {
+	return notImplemented("seed", "usage: gotorrent seed <file.torrent>", args)
+}
+
+// notImplemented still parses the arguments so -h works like for every other command.
+func notImplemented(name string, usage string, args []string) error {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), usage)
+		fmt.Fprintf(flags.Output(), "%s is not implemented yet\n", name)
+	}
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	return errors.New(fmt.Sprintf("%s is not implemented yet", name))
+}
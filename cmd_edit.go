@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"gotorrent/decoder"
+	"gotorrent/editor"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func runEdit(args []string) error {
+	flags := flag.NewFlagSet("edit", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: gotorrent edit [flags] -o <output.torrent> <input.torrent>")
+		fmt.Fprintln(flags.Output(), "changes the trackers & metadata of a torrent, the info dict (and so the info hash) is kept as is")
+		flags.PrintDefaults()
+	}
+
+	var setTrackers, addTrackers, removeTrackers, addWebSeeds, removeWebSeeds stringsFlag
+	flags.Var(&setTrackers, "set-tracker", "replace all the trackers, can be repeated to add tiers, separate urls with ',' to put them in the same tier")
+	flags.Var(&addTrackers, "add-tracker", "append a tier of trackers, can be repeated, separate urls with ',' to put them in the same tier")
+	flags.Var(&removeTrackers, "remove-tracker", "remove a tracker from every tier, can be repeated")
+	order := flags.String("order", "", "new order of the tiers as their current indexes, e.g. '2,0,1'")
+	comment := flags.String("comment", "", "set the comment (an empty string removes it)")
+	createdBy := flags.String("created-by", "", "set created by (an empty string removes it)")
+	flags.Var(&addWebSeeds, "add-web-seed", "add a web seed url, can be repeated")
+	flags.Var(&removeWebSeeds, "remove-web-seed", "remove a web seed url, can be repeated")
+	stripDate := flags.Bool("strip-date", false, "remove the creation date")
+	output := flags.String("o", "", "output file, can be the input file to edit it in place")
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 || *output == "" {
+		flags.Usage()
+		return usageError{"Expected a single input torrent and an output file"}
+	}
+
+	edit := editor.Edit{
+		RemoveTrackers:    removeTrackers,
+		AddWebSeeds:       addWebSeeds,
+		RemoveWebSeeds:    removeWebSeeds,
+		StripCreationDate: *stripDate,
+	}
+	if len(setTrackers) > 0 {
+		edit.SetTrackers = splitTiers(setTrackers)
+	}
+	edit.AddTiers = splitTiers(addTrackers)
+
+	if *order != "" {
+		for _, idx := range strings.Split(*order, ",") {
+			i, err := strconv.Atoi(strings.TrimSpace(idx))
+			if err != nil {
+				return usageError{fmt.Sprintf("Invalid tier index '%s' in -order", idx)}
+			}
+			edit.Reorder = append(edit.Reorder, i)
+		}
+	}
+
+	// an empty -comment or -created-by removes the value so only use them when they're given
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "comment":
+			edit.Comment = comment
+		case "created-by":
+			edit.CreatedBy = createdBy
+		}
+	})
+
+	data, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	torrent, err := decoder.ParseTorrentFile(data)
+	if err != nil {
+		return err
+	}
+	oldHash := torrent.InfoHash()
+	oldInfo := torrent.RawInfo
+
+	if err := editor.Apply(torrent, edit); err != nil {
+		return err
+	}
+
+	encoded, err := editor.Encode(data, torrent)
+	if err != nil {
+		return err
+	}
+
+	// parse what's about to be written to prove the info dict did not change
+	edited, err := decoder.ParseTorrentFile(encoded)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("old info hash: %x\n", oldHash)
+	fmt.Printf("new info hash: %x\n", edited.InfoHash())
+	if !bytes.Equal(edited.RawInfo, oldInfo) {
+		return errors.New("The info dict changed while editing, nothing was written")
+	}
+
+	if err := os.WriteFile(*output, encoded, 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %s\n", *output)
+	return nil
+}
+
+// splitTiers turns repeated tracker flags into tiers, urls separated by ',' share a tier.
+func splitTiers(flags []string) [][]string {
+	tiers := make([][]string, 0, len(flags))
+	for _, tier := range flags {
+		tiers = append(tiers, strings.Split(tier, ","))
+	}
+	return tiers
+}
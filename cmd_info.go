@@ -0,0 +1,226 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"gotorrent/decoder"
+	"gotorrent/editor"
+	"gotorrent/magnet"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// torrentSummary is what "info" shows, the json tags are the --json output.
+type torrentSummary struct {
+	Name         string        `json:"name"`
+	InfoHash     string        `json:"info_hash,omitempty"`
+	InfoHashV2   string        `json:"info_hash_v2,omitempty"`
+	TotalSize    int64         `json:"total_size"`
+	PieceLength  int           `json:"piece_length"`
+	Pieces       int           `json:"pieces"`
+	Private      bool          `json:"private"`
+	Source       string        `json:"source,omitempty"`
+	Comment      string        `json:"comment,omitempty"`
+	CreatedBy    string        `json:"created_by,omitempty"`
+	CreationDate *time.Time    `json:"creation_date,omitempty"`
+	Files        []fileSummary `json:"files"`
+	Trackers     [][]string    `json:"trackers"`
+	WebSeeds     []string      `json:"web_seeds"`
+	Magnet       string        `json:"magnet"`
+}
+
+type fileSummary struct {
+	// Path is relative to the torrent's directory, it's the torrent's name for single file torrents
+	Path   []string `json:"path"`
+	Length int64    `json:"length"`
+}
+
+func runInfo(args []string) error {
+	flags := flag.NewFlagSet("info", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: gotorrent info [flags] <file.torrent>")
+		flags.PrintDefaults()
+	}
+	asJSON := flags.Bool("json", false, "print the information as JSON")
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return usageError{"Expected a single torrent file"}
+	}
+
+	torrent, err := decoder.DecodeTorrentFile(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	summary := summarize(torrent)
+	if *asJSON {
+		return printJSON(os.Stdout, summary)
+	}
+
+	printSummary(os.Stdout, summary)
+	return nil
+}
+
+func printJSON(w io.Writer, s torrentSummary) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(s)
+}
+
+func summarize(t *decoder.TorrentFile) torrentSummary {
+	s := torrentSummary{
+		Name:        t.Info.Name,
+		PieceLength: t.Info.PieceLength,
+		Private:     t.Info.Private,
+		Source:      t.Info.Source,
+		Comment:     t.Comment,
+		CreatedBy:   t.CreatedBy,
+		Files:       make([]fileSummary, 0),
+		Trackers:    editor.Tiers(t),
+		WebSeeds:    append([]string{}, t.URLList...),
+		Magnet:      magnet.FromTorrentFile(t).String(),
+	}
+
+	if t.CreationDate != 0 {
+		date := time.Unix(t.CreationDate, 0).UTC()
+		s.CreationDate = &date
+	}
+
+	if t.Info.HasV1() {
+		hash := t.InfoHash()
+		s.InfoHash = hex.EncodeToString(hash[:])
+		s.Pieces = t.Info.NumPieces()
+
+		for _, f := range t.Info.FileEntries() {
+			if f.IsPadding() {
+				continue
+			}
+			path := f.Path
+			if len(path) == 0 {
+				path = []string{t.Info.Name}
+			}
+			s.Files = append(s.Files, fileSummary{Path: path, Length: f.Length})
+			s.TotalSize += f.Length
+		}
+	}
+
+	if t.Info.HasV2() {
+		hash := t.InfoHashV2()
+		s.InfoHashV2 = hex.EncodeToString(hash[:])
+	}
+
+	// v2 only torrents have no "pieces", each file starts on a new piece
+	if !t.Info.HasV1() {
+		for _, f := range t.Info.FilesV2() {
+			path := f.Path
+			if len(path) == 0 {
+				// a file right at the root of the file tree
+				path = []string{t.Info.Name}
+			}
+			s.Files = append(s.Files, fileSummary{Path: path, Length: f.Length})
+			s.TotalSize += f.Length
+			s.Pieces += int((f.Length + int64(t.Info.PieceLength) - 1) / int64(t.Info.PieceLength))
+		}
+	}
+
+	return s
+}
+
+func printSummary(w io.Writer, s torrentSummary) {
+	fmt.Fprintf(w, "name: %s\n", s.Name)
+	if s.InfoHash != "" {
+		fmt.Fprintf(w, "info hash: %s\n", s.InfoHash)
+	}
+	if s.InfoHashV2 != "" {
+		fmt.Fprintf(w, "v2 info hash: %s\n", s.InfoHashV2)
+	}
+	fmt.Fprintf(w, "total size: %s\n", formatSize(s.TotalSize))
+	fmt.Fprintf(w, "pieces: %d of %s\n", s.Pieces, formatSize(int64(s.PieceLength)))
+	fmt.Fprintf(w, "private: %v\n", s.Private)
+	if s.Source != "" {
+		fmt.Fprintf(w, "source: %s\n", s.Source)
+	}
+	if s.Comment != "" {
+		fmt.Fprintf(w, "comment: %s\n", s.Comment)
+	}
+	if s.CreatedBy != "" {
+		fmt.Fprintf(w, "created by: %s\n", s.CreatedBy)
+	}
+	if s.CreationDate != nil {
+		fmt.Fprintf(w, "creation date: %s\n", s.CreationDate.Format(time.RFC3339))
+	}
+
+	fmt.Fprintln(w, "trackers:")
+	if len(s.Trackers) == 0 {
+		fmt.Fprintln(w, "  none")
+	}
+	for i, tier := range s.Trackers {
+		fmt.Fprintf(w, "  tier %d: %s\n", i, strings.Join(tier, ", "))
+	}
+
+	if len(s.WebSeeds) > 0 {
+		fmt.Fprintln(w, "web seeds:")
+		for _, ws := range s.WebSeeds {
+			fmt.Fprintf(w, "  %s\n", ws)
+		}
+	}
+
+	fmt.Fprintln(w, "files:")
+	printFileTree(w, s.Files)
+
+	fmt.Fprintf(w, "magnet: %s\n", s.Magnet)
+}
+
+// printFileTree prints the files indented under their directories, directories show the size of their content.
+func printFileTree(w io.Writer, files []fileSummary) {
+	dirSizes := make(map[string]int64)
+	for _, f := range files {
+		for i := 1; i < len(f.Path); i++ {
+			dirSizes[strings.Join(f.Path[:i], "/")] += f.Length
+		}
+	}
+
+	var previous []string
+	for _, f := range files {
+		// only print the directories that were not already printed for the previous file
+		common := 0
+		for common < len(previous)-1 && common < len(f.Path)-1 && previous[common] == f.Path[common] {
+			common++
+		}
+
+		for i := common; i < len(f.Path)-1; i++ {
+			dir := strings.Join(f.Path[:i+1], "/")
+			fmt.Fprintf(w, "  %s%s/ (%s)\n", strings.Repeat("  ", i), f.Path[i], formatSize(dirSizes[dir]))
+		}
+
+		depth := len(f.Path) - 1
+		fmt.Fprintf(w, "  %s%s (%s)\n", strings.Repeat("  ", depth), f.Path[depth], formatSize(f.Length))
+		previous = f.Path
+	}
+}
+
+// formatSize shows n in the largest binary unit it has at least one of, e.g. "1.5 GiB".
+func formatSize(n int64) string {
+	units := []string{"KiB", "MiB", "GiB", "TiB", "PiB"}
+	if n < 1024 {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	size := float64(n) / 1024
+	unit := 0
+	for size >= 1024 && unit < len(units)-1 {
+		size /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.1f %s", size, units[unit])
+}
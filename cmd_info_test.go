@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"gotorrent/decoder"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// v2Summary builds a v2 only torrent with nested directories, nothing is hashed
+// since the summary only shows the metadata.
+func v2Summary() torrentSummary {
+	root := strings.Repeat("r", 32)
+	torrent := &decoder.TorrentFile{
+		Announce: "http://tracker/announce?a=1&b=2",
+		Comment:  "<nested> & more",
+		Info: decoder.TorrentInfo{
+			Name:        "dir",
+			PieceLength: 16384,
+			MetaVersion: 2,
+			FileTree: &decoder.FileTree{Children: map[string]*decoder.FileTree{
+				"sub": {Children: map[string]*decoder.FileTree{
+					"big.bin": {File: &decoder.FileTreeEntry{Length: 40000, PiecesRoot: root}},
+					"deep":    {Children: map[string]*decoder.FileTree{"c": {File: &decoder.FileTreeEntry{Length: 5, PiecesRoot: root}}}},
+				}},
+				"a.txt": {File: &decoder.FileTreeEntry{Length: 100, PiecesRoot: root}},
+			}},
+		},
+	}
+	return summarize(torrent)
+}
+
+func TestPrintFileTree(t *testing.T) {
+	s := v2Summary()
+
+	expectedFiles := []fileSummary{
+		{Path: []string{"a.txt"}, Length: 100},
+		{Path: []string{"sub", "big.bin"}, Length: 40000},
+		{Path: []string{"sub", "deep", "c"}, Length: 5},
+	}
+	if !reflect.DeepEqual(s.Files, expectedFiles) || s.TotalSize != 40105 || s.Pieces != 5 {
+		t.Errorf("unexpected summary %+v", s)
+	}
+
+	var out bytes.Buffer
+	printFileTree(&out, s.Files)
+	expected := "  a.txt (100 B)\n" +
+		"  sub/ (39.1 KiB)\n" +
+		"    big.bin (39.1 KiB)\n" +
+		"    deep/ (5 B)\n" +
+		"      c (5 B)\n"
+	if out.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s\ninstead", expected, out.String())
+	}
+
+	out.Reset()
+	printSummary(&out, s)
+	for _, line := range []string{"name: dir\n", "v2 info hash: " + s.InfoHashV2 + "\n", "pieces: 5 of 16.0 KiB\n", "  tier 0: http://tracker/announce?a=1&b=2\n", "files:\n" + expected} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("expected the summary to contain %q got\n%s\ninstead", line, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "info hash: \n") {
+		t.Errorf("expected no v1 info hash for a v2 only torrent")
+	}
+}
+
+func TestPrintJSON(t *testing.T) {
+	s := v2Summary()
+
+	var out bytes.Buffer
+	if err := printJSON(&out, s); err != nil {
+		t.Fatalf("expected no error got %s instead", err)
+	}
+
+	// '<', '>' and '&' are kept as is
+	if !strings.Contains(out.String(), `"comment": "<nested> & more"`) {
+		t.Errorf("expected the comment to not be escaped got %s instead", out.String())
+	}
+
+	var res map[string]any
+	if err := json.Unmarshal(out.Bytes(), &res); err != nil {
+		t.Fatalf("expected valid JSON got %s instead", err)
+	}
+	if _, ok := res["info_hash"]; ok {
+		t.Errorf("expected no v1 info hash got %v instead", res["info_hash"])
+	}
+	if res["info_hash_v2"] != s.InfoHashV2 || res["total_size"] != float64(40105) || res["private"] != false {
+		t.Errorf("unexpected JSON %v", res)
+	}
+
+	expectedFiles := []any{
+		map[string]any{"path": []any{"a.txt"}, "length": float64(100)},
+		map[string]any{"path": []any{"sub", "big.bin"}, "length": float64(40000)},
+		map[string]any{"path": []any{"sub", "deep", "c"}, "length": float64(5)},
+	}
+	if !reflect.DeepEqual(res["files"], expectedFiles) {
+		t.Errorf("expected files %v got %v instead", expectedFiles, res["files"])
+	}
+	if !reflect.DeepEqual(res["web_seeds"], []any{}) || !reflect.DeepEqual(res["trackers"], []any{[]any{"http://tracker/announce?a=1&b=2"}}) {
+		t.Errorf("unexpected trackers %v & web seeds %v", res["trackers"], res["web_seeds"])
+	}
+}
+
+func TestInfoUnnamedV2File(t *testing.T) {
+	input := "d4:infod9:file treed0:d6:lengthi5e11:pieces root32:" + strings.Repeat("r", 32) +
+		"ee12:meta versioni2e4:name1:x12:piece lengthi16384eee"
+
+	// parsing rejects it
+	path := filepath.Join(t.TempDir(), "x.torrent")
+	if err := os.WriteFile(path, []byte(input), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := runInfo([]string{path}); err == nil {
+		t.Errorf("expected an error for a file at the root of the file tree")
+	}
+
+	// the summary falls back to the torrent's name instead of an empty path
+	torrent, err := decoder.ParseUnvalidated([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := summarize(torrent)
+	expected := []fileSummary{{Path: []string{"x"}, Length: 5}}
+	if !reflect.DeepEqual(s.Files, expected) {
+		t.Errorf("expected files %+v got %+v instead", expected, s.Files)
+	}
+
+	var out bytes.Buffer
+	printFileTree(&out, s.Files)
+	if out.String() != "  x (5 B)\n" {
+		t.Errorf("expected the file to be shown under the torrent's name got %q instead", out.String())
+	}
+}
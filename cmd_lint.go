@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"gotorrent/decoder"
+	"os"
+)
+
+func runLint(args []string) error {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: gotorrent lint [flags] <file.torrent>...")
+		fmt.Fprintln(flags.Output(), "checks torrents for invalid or unsafe metadata, exits with 1 when errors are found")
+		flags.PrintDefaults()
+	}
+	strict := flags.Bool("strict", false, "fail on warnings too")
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return usageError{"Expected at least one torrent file"}
+	}
+
+	failed := 0
+	for _, path := range flags.Args() {
+		problems, err := lintFile(path)
+		if err != nil {
+			fmt.Printf("%s: error: %s\n", path, err)
+			failed++
+			continue
+		}
+
+		for _, p := range problems {
+			fmt.Printf("%s: %s\n", path, p)
+		}
+		if decoder.HasErrors(problems) || (*strict && len(problems) > 0) {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return errors.New(fmt.Sprintf("%d of %d torrents failed the checks", failed, flags.NArg()))
+	}
+	return nil
+}
+
+func lintFile(path string) ([]decoder.Problem, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	torrent, err := decoder.ParseUnvalidated(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return decoder.Validate(torrent), nil
+}
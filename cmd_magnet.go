@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"gotorrent/decoder"
+	"gotorrent/magnet"
+	"strings"
+)
+
+func runMagnet(args []string) error {
+	flags := flag.NewFlagSet("magnet", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: gotorrent magnet <file.torrent | magnet link>")
+		fmt.Fprintln(flags.Output(), "prints the magnet link of a torrent file, or what a magnet link holds")
+		flags.PrintDefaults()
+	}
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return usageError{"Expected a single torrent file or magnet link"}
+	}
+
+	if strings.HasPrefix(strings.ToLower(flags.Arg(0)), "magnet:") {
+		m, err := magnet.Parse(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		printMagnet(m)
+		return nil
+	}
+
+	torrent, err := decoder.DecodeTorrentFile(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(magnet.FromTorrentFile(torrent))
+	return nil
+}
+
+func printMagnet(m *magnet.Magnet) {
+	if m.HasV1() {
+		fmt.Printf("info hash: %x\n", m.InfoHash)
+	}
+	if m.HasV2() {
+		fmt.Printf("v2 info hash: %x\n", m.InfoHashV2)
+	}
+	if m.DisplayName != "" {
+		fmt.Printf("name: %s\n", m.DisplayName)
+	}
+	for _, tr := range m.Trackers {
+		fmt.Printf("tracker: %s\n", tr)
+	}
+	for _, ws := range m.WebSeeds {
+		fmt.Printf("web seed: %s\n", ws)
+	}
+	for _, peer := range m.Peers {
+		fmt.Printf("peer: %s\n", peer)
+	}
+	for _, r := range m.SelectOnly {
+		if r.First == r.Last {
+			fmt.Printf("selected file: %d\n", r.First)
+		} else {
+			fmt.Printf("selected files: %d-%d\n", r.First, r.Last)
+		}
+	}
+}
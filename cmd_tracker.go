@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"gotorrent/decoder"
+	trackerclient "gotorrent/tracker_client"
+	"net"
+	"strconv"
+)
+
+func runTracker(args []string) error {
+	flags := flag.NewFlagSet("tracker", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: gotorrent tracker [flags] <file.torrent>")
+		fmt.Fprintln(flags.Output(), "sends a single announce to the torrent's tracker and prints the peers it returns")
+		flags.PrintDefaults()
+	}
+	tracker := flags.String("tracker", "", "announce to this tracker instead of the torrent's 'announce'")
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return usageError{"Expected a single torrent file"}
+	}
+
+	torrent, err := decoder.DecodeTorrentFile(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *tracker != "" {
+		torrent.Announce = *tracker
+	}
+	if torrent.Announce == "" {
+		return usageError{"Torrent has no tracker, use -tracker to give one"}
+	}
+
+	client, err := trackerclient.NewTrackerClient(*torrent)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Announce()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("tracker: %s\n", torrent.Announce)
+	fmt.Printf("seeders: %d, leechers: %d, next announce in %ds\n", resp.Seeders, resp.Leechers, resp.Interval)
+	fmt.Printf("peers (%d):\n", len(resp.Peers))
+	for _, peer := range resp.Peers {
+		fmt.Printf("  %s\n", net.JoinHostPort(peer.Ip.String(), strconv.Itoa(int(peer.Port))))
+	}
+	return nil
+}
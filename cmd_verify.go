@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"gotorrent/decoder"
+	"gotorrent/verifier"
+)
+
+func runVerify(args []string) error {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: gotorrent verify [flags] <file.torrent>")
+		fmt.Fprintln(flags.Output(), "checks the data of a torrent against its hashes, exits with 1 when it's incomplete")
+		flags.PrintDefaults()
+	}
+	dir := flags.String("dir", ".", "directory the torrent was downloaded to (the one holding the torrent's name)")
+	workers := flags.Int("workers", 0, "number of pieces hashed in parallel (defaults to the number of CPUs)")
+	verbose := flags.Bool("v", false, "list every file instead of only the incomplete ones")
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return usageError{"Expected a single torrent file"}
+	}
+
+	torrent, err := decoder.DecodeTorrentFile(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	res, err := verifier.Verify(torrent, *dir, *workers)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range res.Files {
+		switch {
+		case f.Missing:
+			fmt.Printf("missing     %s\n", f.Path)
+		case !f.Complete:
+			fmt.Printf("incomplete  %s\n", f.Path)
+		case *verbose:
+			fmt.Printf("ok          %s\n", f.Path)
+		}
+	}
+
+	if torrent.Info.HasV1() {
+		fmt.Printf("%d of %d pieces are valid\n", res.ValidPieces(), len(res.Pieces))
+	}
+
+	if !res.Complete() {
+		return errors.New("Torrent data is incomplete")
+	}
+	fmt.Println("Torrent data is complete")
+	return nil
+}
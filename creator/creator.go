@@ -0,0 +1,349 @@
+package creator
+
+import (
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"gotorrent/bencode"
+	"gotorrent/decoder"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	MinPieceLength = 16 * 1024
+	MaxPieceLength = 16 * 1024 * 1024
+	// targetPieces is roughly how many pieces AutoPieceLength aims for
+	targetPieces = 1500
+)
+
+type Options struct {
+	// Name defaults to the base name of the file or directory
+	Name string
+	// PieceLength is picked by AutoPieceLength when 0
+	PieceLength int
+	// AnnounceList holds the tiers of trackers, the first tracker is also used as "announce"
+	AnnounceList [][]string
+	Comment      string
+	CreatedBy    string
+	// CreationDate is not written when it's the zero time
+	CreationDate time.Time
+	Private      bool
+	WebSeeds     []string
+	Source       string
+	// Ignore holds glob patterns (see filepath.Match) matched against both the base name
+	// and the path relative to the torrent root of each file & directory, e.g. ".git" or "*.tmp"
+	Ignore []string
+	// Workers is how many pieces are hashed in parallel, it defaults to the number of CPUs
+	Workers int
+	// Hybrid adds v2 (BEP 52) metadata next to the v1 one so both kinds of clients can use the torrent,
+	// files are then aligned on pieces with padding files (BEP 47)
+	Hybrid bool
+}
+
+// diskFile is a file found while walking the input along with where it's on disk.
+type diskFile struct {
+	entry decoder.FileEntry
+	path  string
+}
+
+// Create builds a torrent out of a file or a directory (recursively).
+// Files are added in lexical order and only regular files are included.
+func Create(root string, opts Options) (*decoder.TorrentFile, error) {
+	files, err := walk(root, opts.Ignore)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+
+	name := opts.Name
+	if name == "" {
+		name = filepath.Base(filepath.Clean(root))
+	}
+
+	info := decoder.TorrentInfo{
+		Name:        name,
+		PieceLength: opts.PieceLength,
+		Private:     opts.Private,
+		Source:      opts.Source,
+	}
+
+	if info.PieceLength == 0 {
+		var total int64
+		for _, f := range files {
+			total += f.entry.Length
+		}
+		info.PieceLength = AutoPieceLength(total)
+	}
+	if info.PieceLength <= 0 {
+		return nil, errors.New(fmt.Sprintf("Expected a positive piece length got %d instead", info.PieceLength))
+	}
+
+	v1Files := files
+	if opts.Hybrid {
+		if err := checkHybridPieceLength(info.PieceLength); err != nil {
+			return nil, err
+		}
+		v1Files = addPadding(files, info.PieceLength)
+	}
+
+	if stat.IsDir() {
+		info.Files = make([]decoder.FileEntry, len(v1Files))
+		for i, f := range v1Files {
+			info.Files[i] = f.entry
+		}
+	} else {
+		info.Length = files[0].entry.Length
+	}
+
+	numPieces := (info.TotalLength() + int64(info.PieceLength) - 1) / int64(info.PieceLength)
+	pieces, err := hashPieces(info, v1Files, int(numPieces), opts.Workers)
+	if err != nil {
+		return nil, err
+	}
+	info.Pieces = pieces
+
+	t := newTorrentFile(info, opts)
+	if opts.Hybrid {
+		if err := addV2(t, files, opts.Workers); err != nil {
+			return nil, err
+		}
+	}
+
+	// keep the exact bytes that get written so the info hashes match the file
+	raw, err := bencode.Marshal(t.Info)
+	if err != nil {
+		return nil, err
+	}
+	t.RawInfo = raw
+
+	return t, nil
+}
+
+func newTorrentFile(info decoder.TorrentInfo, opts Options) *decoder.TorrentFile {
+	t := &decoder.TorrentFile{
+		Comment:   opts.Comment,
+		CreatedBy: opts.CreatedBy,
+		Info:      info,
+		URLList:   opts.WebSeeds,
+	}
+
+	if len(opts.AnnounceList) > 0 && len(opts.AnnounceList[0]) > 0 {
+		t.Announce = opts.AnnounceList[0][0]
+	}
+	// a single tracker doesn't need an announce list
+	if len(opts.AnnounceList) > 1 || (len(opts.AnnounceList) == 1 && len(opts.AnnounceList[0]) > 1) {
+		t.AnnounceList = opts.AnnounceList
+	}
+
+	if !opts.CreationDate.IsZero() {
+		t.CreationDate = opts.CreationDate.Unix()
+	}
+
+	return t
+}
+
+// AutoPieceLength picks the smallest power of two (between MinPieceLength and MaxPieceLength)
+// that splits totalLength into at most about 1500 pieces.
+func AutoPieceLength(totalLength int64) int {
+	pieceLength := MinPieceLength
+	for pieceLength < MaxPieceLength && totalLength/int64(pieceLength) > targetPieces {
+		pieceLength *= 2
+	}
+	return pieceLength
+}
+
+func walk(root string, ignore []string) ([]diskFile, error) {
+	for _, pattern := range ignore {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid ignore pattern '%s': %s", pattern, err))
+		}
+	}
+
+	files := make([]diskFile, 0)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		if rel != "." && isIgnored(rel, ignore) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// directories are walked into, anything else that's not a regular file (symlinks, sockets...) is skipped
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		stat, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		entry := decoder.FileEntry{Length: stat.Size(), Path: []string{}}
+		if rel != "." {
+			entry.Path = strings.Split(filepath.ToSlash(rel), "/")
+		}
+		files = append(files, diskFile{entry: entry, path: path})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) == 0 {
+		return nil, errors.New(fmt.Sprintf("No files found in %s", root))
+	}
+
+	return files, nil
+}
+
+func isIgnored(rel string, ignore []string) bool {
+	for _, pattern := range ignore {
+		if ok, _ := filepath.Match(pattern, filepath.Base(rel)); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, filepath.ToSlash(rel)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// hashPieces computes the SHA-1 of every piece, pieces are handed out to "workers" goroutines
+// each one reading its pieces straight from the files they span.
+func hashPieces(info decoder.TorrentInfo, files []diskFile, numPieces int, workers int) (decoder.PieceHashes, error) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	// PieceSegments needs to know how many pieces there are
+	pieces := make(decoder.PieceHashes, numPieces)
+	info.Pieces = pieces
+
+	jobs := make(chan int)
+	done := make(chan struct{})
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			r := pieceReader{files: files, open: make(map[int]*os.File)}
+			defer r.close()
+
+			buf := make([]byte, info.PieceLength)
+			for idx := range jobs {
+				n, err := r.readPiece(buf, info.PieceSegments(idx))
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = err
+						close(done)
+					})
+					return
+				}
+
+				pieces[idx] = sha1.Sum(buf[:n])
+			}
+		}()
+	}
+
+feed:
+	for idx := range numPieces {
+		select {
+		case jobs <- idx:
+		case <-done:
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return pieces, nil
+}
+
+// pieceReader reads pieces out of the files, the last file read is kept open for the next piece.
+type pieceReader struct {
+	files []diskFile
+	open  map[int]*os.File
+}
+
+func (r *pieceReader) readPiece(buf []byte, segments []decoder.FileSegment) (int, error) {
+	n := 0
+	for _, seg := range segments {
+		if r.files[seg.File].entry.IsPadding() {
+			clear(buf[n : n+int(seg.Length)])
+			n += int(seg.Length)
+			continue
+		}
+
+		f, err := r.file(seg.File)
+		if err != nil {
+			return 0, err
+		}
+
+		if _, err := f.ReadAt(buf[n:n+int(seg.Length)], seg.Offset); err != nil {
+			if err == io.EOF {
+				return 0, errors.New(fmt.Sprintf("File %s got smaller while being hashed", r.files[seg.File].path))
+			}
+			return 0, err
+		}
+		n += int(seg.Length)
+	}
+
+	// only the last file can be needed by the next piece, don't keep the others open
+	// since a torrent can have thousands of files
+	for idx, f := range r.open {
+		if len(segments) == 0 || idx != segments[len(segments)-1].File {
+			f.Close()
+			delete(r.open, idx)
+		}
+	}
+
+	return n, nil
+}
+
+func (r *pieceReader) file(idx int) (*os.File, error) {
+	if f, ok := r.open[idx]; ok {
+		return f, nil
+	}
+
+	f, err := os.Open(r.files[idx].path)
+	if err != nil {
+		return nil, err
+	}
+	r.open[idx] = f
+	return f, nil
+}
+
+func (r *pieceReader) close() {
+	for _, f := range r.open {
+		f.Close()
+	}
+}
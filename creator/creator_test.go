@@ -0,0 +1,261 @@
+package creator
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"gotorrent/decoder"
+	"gotorrent/encoder"
+	"gotorrent/internal/testutil"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// writeFiles creates the given files (slash separated paths) under a temp dir.
+// expectedPieces hashes the concatenated data one piece after the other.
+func expectedPieces(data []byte, pieceLength int) decoder.PieceHashes {
+	var pieces decoder.PieceHashes
+	for start := 0; start < len(data); start += pieceLength {
+		pieces = append(pieces, sha1.Sum(data[start:min(start+pieceLength, len(data))]))
+	}
+	return pieces
+}
+
+func TestCreateDirectory(t *testing.T) {
+	a, b, c := testutil.Content(50000, 1), testutil.Content(10, 2), testutil.Content(40000, 3)
+	root := testutil.WriteFiles(t, map[string][]byte{
+		"a.bin":         a,
+		"sub/b.txt":     b,
+		"sub/c.bin":     c,
+		"sub/empty":     {},
+		"skip.tmp":      testutil.Content(5, 4),
+		".git/HEAD":     testutil.Content(5, 5),
+		"sub/.git/HEAD": testutil.Content(5, 6),
+	})
+
+	for _, workers := range []int{1, 3, 0} {
+		torrent, err := Create(root, Options{
+			Name:         "release",
+			PieceLength:  16384,
+			AnnounceList: [][]string{{"http://t1", "http://t2"}, {"udp://t3"}},
+			Comment:      "comment",
+			CreatedBy:    "gotorrent",
+			CreationDate: time.Unix(1700000000, 0),
+			Private:      true,
+			WebSeeds:     []string{"http://seed"},
+			Source:       "SRC",
+			Ignore:       []string{"*.tmp", ".git"},
+			Workers:      workers,
+		})
+		if err != nil {
+			t.Fatalf("expected no error got %s instead", err)
+		}
+
+		expectedFiles := []decoder.FileEntry{
+			{Length: 50000, Path: []string{"a.bin"}},
+			{Length: 10, Path: []string{"sub", "b.txt"}},
+			{Length: 40000, Path: []string{"sub", "c.bin"}},
+			{Length: 0, Path: []string{"sub", "empty"}},
+		}
+		if !reflect.DeepEqual(torrent.Info.Files, expectedFiles) {
+			t.Errorf("expected files %+v got %+v instead", expectedFiles, torrent.Info.Files)
+		}
+
+		data := append(append(append([]byte{}, a...), b...), c...)
+		if !reflect.DeepEqual(torrent.Info.Pieces, expectedPieces(data, 16384)) {
+			t.Errorf("unexpected pieces with %d workers", workers)
+		}
+
+		if torrent.Announce != "http://t1" || len(torrent.AnnounceList) != 2 || torrent.Comment != "comment" ||
+			torrent.CreationDate != 1700000000 || !torrent.Info.Private || torrent.Info.Source != "SRC" ||
+			torrent.Info.Name != "release" || !reflect.DeepEqual([]string(torrent.URLList), []string{"http://seed"}) {
+			t.Errorf("unexpected metadata %+v", torrent)
+		}
+
+		// what gets written parses back to the same torrent
+		encoded, err := encoder.Encode(torrent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, err := decoder.ParseTorrentFile([]byte(encoded))
+		if err != nil {
+			t.Fatalf("expected no error got %s instead", err)
+		}
+		if parsed.InfoHash() != torrent.InfoHash() || !reflect.DeepEqual(parsed, torrent) {
+			t.Errorf("expected the written torrent to parse back to the created one")
+		}
+	}
+}
+
+func TestCreateSingleFile(t *testing.T) {
+	content := testutil.Content(100000, 7)
+	root := testutil.WriteFiles(t, map[string][]byte{"file.iso": content})
+
+	torrent, err := Create(filepath.Join(root, "file.iso"), Options{})
+	if err != nil {
+		t.Fatalf("expected no error got %s instead", err)
+	}
+
+	if torrent.Info.IsMultiFile() || torrent.Info.Length != 100000 || torrent.Info.Name != "file.iso" {
+		t.Errorf("unexpected info %+v", torrent.Info)
+	}
+	if torrent.Info.PieceLength != MinPieceLength || !reflect.DeepEqual(torrent.Info.Pieces, expectedPieces(content, MinPieceLength)) {
+		t.Errorf("unexpected pieces")
+	}
+	// no trackers, no date
+	if torrent.Announce != "" || torrent.AnnounceList != nil || torrent.CreationDate != 0 {
+		t.Errorf("unexpected metadata %+v", torrent)
+	}
+
+	encoded, _ := encoder.Encode(torrent)
+	if bytes.Contains([]byte(encoded), []byte("announce")) {
+		t.Errorf("expected no announce key got %s instead", encoded[:50])
+	}
+}
+
+func TestCreateErrors(t *testing.T) {
+	root := testutil.WriteFiles(t, map[string][]byte{"a.tmp": {1}})
+
+	tests := []struct {
+		path string
+		opts Options
+	}{
+		{filepath.Join(root, "missing"), Options{}},
+		// everything is ignored
+		{root, Options{Ignore: []string{"*.tmp"}}},
+		{root, Options{Ignore: []string{"["}}},
+		{root, Options{PieceLength: -1}},
+	}
+
+	for _, test := range tests {
+		if _, err := Create(test.path, test.opts); err == nil {
+			t.Errorf("expected an error for %s with %+v", test.path, test.opts)
+		}
+	}
+}
+
+func TestAutoPieceLength(t *testing.T) {
+	tests := []struct {
+		length   int64
+		expected int
+	}{
+		{0, MinPieceLength},
+		{1500 * MinPieceLength, MinPieceLength},
+		{1500*MinPieceLength + MinPieceLength, 2 * MinPieceLength},
+		{6203355136, 4 * 1024 * 1024},
+		{1 << 50, MaxPieceLength},
+	}
+
+	for _, test := range tests {
+		if res := AutoPieceLength(test.length); res != test.expected {
+			t.Errorf("expected %d for %d bytes got %d instead", test.expected, test.length, res)
+		}
+	}
+}
+
+func TestCreateHybrid(t *testing.T) {
+	pieceLength := 2 * decoder.BlockSize
+	a, b, c := testutil.Content(3*pieceLength+100, 1), testutil.Content(pieceLength, 2), testutil.Content(10, 3)
+	root := testutil.WriteFiles(t, map[string][]byte{
+		"a.bin":     a,
+		"b.bin":     b,
+		"sub/c.txt": c,
+		"sub/empty": {},
+	})
+
+	torrent, err := Create(root, Options{PieceLength: pieceLength, Hybrid: true, Workers: 2})
+	if err != nil {
+		t.Fatalf("expected no error got %s instead", err)
+	}
+
+	padding := int64(pieceLength - 100)
+	expectedFiles := []decoder.FileEntry{
+		{Length: int64(len(a)), Path: []string{"a.bin"}},
+		{Length: padding, Path: []string{".pad", strconv.FormatInt(padding, 10)}, Attr: "p"},
+		// b is already aligned
+		{Length: int64(len(b)), Path: []string{"b.bin"}},
+		{Length: 10, Path: []string{"sub", "c.txt"}},
+		{Length: int64(pieceLength - 10), Path: []string{".pad", strconv.Itoa(pieceLength - 10)}, Attr: "p"},
+		{Length: 0, Path: []string{"sub", "empty"}},
+	}
+	if !reflect.DeepEqual(torrent.Info.Files, expectedFiles) {
+		t.Errorf("expected files %+v got %+v instead", expectedFiles, torrent.Info.Files)
+	}
+
+	var data []byte
+	data = append(data, a...)
+	data = append(data, make([]byte, padding)...)
+	data = append(data, b...)
+	data = append(data, c...)
+	data = append(data, make([]byte, pieceLength-10)...)
+	if !reflect.DeepEqual(torrent.Info.Pieces, expectedPieces(data, pieceLength)) {
+		t.Errorf("unexpected v1 pieces")
+	}
+
+	encoded, err := encoder.Encode(torrent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := decoder.ParseTorrentFile([]byte(encoded))
+	if err != nil {
+		t.Fatalf("expected no error got %s instead", err)
+	}
+
+	if !parsed.Info.IsHybrid() {
+		t.Fatalf("expected a hybrid torrent")
+	}
+	if err := parsed.VerifyPieceLayers(); err != nil {
+		t.Errorf("expected valid piece layers got %s instead", err)
+	}
+	if parsed.InfoHash() != torrent.InfoHash() || parsed.InfoHashV2() != torrent.InfoHashV2() {
+		t.Errorf("expected both hashes to match the written file")
+	}
+
+	// v1 & v2 describe the same files
+	v1 := make([]decoder.FileEntry, 0)
+	for _, f := range parsed.Info.Files {
+		if !f.IsPadding() {
+			v1 = append(v1, decoder.FileEntry{Length: f.Length, Path: f.Path})
+		}
+	}
+	v2 := make([]decoder.FileEntry, 0)
+	for _, f := range parsed.Info.FilesV2() {
+		v2 = append(v2, decoder.FileEntry{Length: f.Length, Path: f.Path})
+
+		content := map[string][]byte{"a.bin": a, "b.bin": b, "c.txt": c, "empty": {}}[f.Path[len(f.Path)-1]]
+		hash, _ := decoder.HashFileV2(bytes.NewReader(content), pieceLength)
+		if f.Length > 0 && f.PiecesRoot != string(hash.PiecesRoot[:]) {
+			t.Errorf("unexpected pieces root for %v", f.Path)
+		}
+	}
+	if !reflect.DeepEqual(v1, v2) {
+		t.Errorf("expected the v1 files %+v to match the v2 ones %+v", v1, v2)
+	}
+
+	if _, err := Create(root, Options{PieceLength: 3 * decoder.BlockSize, Hybrid: true}); err == nil {
+		t.Errorf("expected an error when the piece length is not a power of two")
+	}
+}
+
+func TestCreateHybridSingleFile(t *testing.T) {
+	content := testutil.Content(5*decoder.BlockSize+1, 9)
+	root := testutil.WriteFiles(t, map[string][]byte{"file.iso": content})
+
+	torrent, err := Create(filepath.Join(root, "file.iso"), Options{PieceLength: 2 * decoder.BlockSize, Hybrid: true})
+	if err != nil {
+		t.Fatalf("expected no error got %s instead", err)
+	}
+
+	files := torrent.Info.FilesV2()
+	if len(files) != 1 || !reflect.DeepEqual(files[0].Path, []string{"file.iso"}) || files[0].Length != int64(len(content)) {
+		t.Errorf("unexpected v2 files %+v", files)
+	}
+	if torrent.Info.Length != int64(len(content)) || !reflect.DeepEqual(torrent.Info.Pieces, expectedPieces(content, 2*decoder.BlockSize)) {
+		t.Errorf("unexpected v1 info")
+	}
+	if err := torrent.VerifyPieceLayers(); err != nil {
+		t.Errorf("expected valid piece layers got %s instead", err)
+	}
+}
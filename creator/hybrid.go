@@ -0,0 +1,134 @@
+package creator
+
+import (
+	"errors"
+	"fmt"
+	"gotorrent/decoder"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+// addPadding inserts a BEP 47 padding file after each file that does not end on a piece boundary
+// (except the last one), so every file of a hybrid torrent starts on a new piece
+// and the v1 pieces line up with the v2 ones.
+func addPadding(files []diskFile, pieceLength int) []diskFile {
+	padded := make([]diskFile, 0, len(files)*2)
+	for i, f := range files {
+		padded = append(padded, f)
+
+		rest := f.entry.Length % int64(pieceLength)
+		if i == len(files)-1 || rest == 0 {
+			continue
+		}
+
+		size := int64(pieceLength) - rest
+		padded = append(padded, diskFile{entry: decoder.FileEntry{
+			Length: size,
+			Path:   []string{".pad", strconv.FormatInt(size, 10)},
+			Attr:   "p",
+		}})
+	}
+	return padded
+}
+
+// addV2 hashes every file for v2 and fills in the "file tree" & "piece layers".
+func addV2(t *decoder.TorrentFile, files []diskFile, workers int) error {
+	hashes, err := hashFilesV2(files, t.Info.PieceLength, workers)
+	if err != nil {
+		return err
+	}
+
+	tree := &decoder.FileTree{}
+	for i, f := range files {
+		entry := &decoder.FileTreeEntry{Length: f.entry.Length, Attr: f.entry.Attr}
+		if hashes[i].Length > 0 {
+			entry.PiecesRoot = string(hashes[i].PiecesRoot[:])
+		}
+
+		if len(hashes[i].PieceLayer) > 0 {
+			if t.PieceLayers == nil {
+				t.PieceLayers = make(map[string]string)
+			}
+			t.PieceLayers[entry.PiecesRoot] = string(hashes[i].PieceLayer)
+		}
+
+		// a single file torrent has the file named after the torrent at the top of the tree
+		path := f.entry.Path
+		if len(path) == 0 {
+			path = []string{t.Info.Name}
+		}
+
+		node := tree
+		for _, name := range path {
+			if node.Children == nil {
+				node.Children = make(map[string]*decoder.FileTree)
+			}
+			if node.Children[name] == nil {
+				node.Children[name] = &decoder.FileTree{}
+			}
+			node = node.Children[name]
+		}
+		node.File = entry
+	}
+
+	t.Info.MetaVersion = 2
+	t.Info.FileTree = tree
+	return nil
+}
+
+// hashFilesV2 builds the merkle tree of each file, one file per worker at a time.
+func hashFilesV2(files []diskFile, pieceLength int, workers int) ([]decoder.FileHashV2, error) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	hashes := make([]decoder.FileHashV2, len(files))
+	errs := make([]error, len(files))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				hashes[idx], errs[idx] = hashFileV2(files[idx], pieceLength)
+			}
+		}()
+	}
+
+	for idx := range files {
+		jobs <- idx
+	}
+	close(jobs)
+	wg.Wait()
+
+	return hashes, errors.Join(errs...)
+}
+
+func hashFileV2(f diskFile, pieceLength int) (decoder.FileHashV2, error) {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return decoder.FileHashV2{}, err
+	}
+	defer file.Close()
+
+	hash, err := decoder.HashFileV2(file, pieceLength)
+	if err != nil {
+		return decoder.FileHashV2{}, err
+	}
+
+	if hash.Length != f.entry.Length {
+		return decoder.FileHashV2{}, errors.New(fmt.Sprintf("File %s changed size while being hashed", f.path))
+	}
+	return hash, nil
+}
+
+func checkHybridPieceLength(pieceLength int) error {
+	if pieceLength < decoder.BlockSize || pieceLength&(pieceLength-1) != 0 {
+		return errors.New(fmt.Sprintf("Hybrid torrents need a piece length that's a power of two of at least %d got %d instead", decoder.BlockSize, pieceLength))
+	}
+	return nil
+}
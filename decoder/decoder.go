@@ -1,29 +1,109 @@
 package decoder
 
 import (
+	"bytes"
+	"crypto/sha1"
 	"errors"
 	"fmt"
-	"gotorrent/utils"
+	"gotorrent/bencode"
 	"os"
-	"strconv"
-	"strings"
 	"time"
 )
 
 type TorrentFile struct {
-	Announce     string
-	AnnounceList [][]string
-	CreatedBy    string
-	CreationDate int
-	Encoding     string
-	Info         TorrentInfo
+	Announce     string      `bencode:"announce,omitempty"`
+	AnnounceList [][]string  `bencode:"announce-list,omitempty"`
+	Comment      string      `bencode:"comment,omitempty"`
+	CreatedBy    string      `bencode:"created by,omitempty"`
+	CreationDate int64       `bencode:"creation date,omitempty"`
+	Encoding     string      `bencode:"encoding,omitempty"`
+	Info         TorrentInfo `bencode:"info"`
+	// URLList holds the web seeds (BEP 19)
+	URLList URLList `bencode:"url-list,omitempty"`
+	// HTTPSeeds holds the BEP 17 seeds, unlike "url-list" they're not plain web servers
+	HTTPSeeds URLList `bencode:"httpseeds,omitempty"`
+	// Nodes are the DHT nodes trackerless torrents bootstrap from
+	Nodes []Node `bencode:"nodes,omitempty"`
+	// PieceLayers maps the pieces root of each v2 file larger than a piece to its piece hashes
+	PieceLayers map[string]string `bencode:"piece layers,omitempty"`
+	// Extra keeps the keys we don't know about so they're written back as is
+	Extra map[string]bencode.RawMessage `bencode:",extra"`
+
+	// RawInfo holds the "info" dict exactly as it was found in the file,
+	// the info hash has to be computed from these bytes and not from a re-encoded "Info".
+	// It's also what gets written by MarshalBencode so clear it after changing "Info".
+	RawInfo bencode.RawMessage `bencode:"-"`
+
+	// urlListString is set when "url-list" was a single string instead of a list
+	urlListString bool
 }
 
 type TorrentInfo struct {
-	Length      int
-	Name        string
-	PieceLength int
-	Pieces      string
+	// a torrent has either "length" (single file) or "files" (multi-file), see FileEntries
+	Length      int64       `bencode:"length,omitempty"`
+	Files       []FileEntry `bencode:"files,omitempty"`
+	Name        string      `bencode:"name"`
+	PieceLength int         `bencode:"piece length"`
+	Pieces      PieceHashes `bencode:"pieces,omitempty"`
+	// Private torrents (BEP 27) only get peers from their trackers
+	Private bool   `bencode:"private,omitempty"`
+	Source  string `bencode:"source,omitempty"`
+
+	// v2 (BEP 52) metadata, a hybrid torrent has both v1 & v2 metadata
+	MetaVersion int       `bencode:"meta version,omitempty"`
+	FileTree    *FileTree `bencode:"file tree,omitempty"`
+
+	// Similar holds the info hashes of torrents sharing files with this one
+	// and Collections the names of collections it belongs to (BEP 38)
+	Similar     [][20]byte `bencode:"similar,omitempty"`
+	Collections []string   `bencode:"collections,omitempty"`
+
+	// Extra keeps the keys we don't know about, they're part of the info hash
+	// so re-encoding the info dict without them would give a different torrent
+	Extra map[string]bencode.RawMessage `bencode:",extra"`
+}
+
+// InfoHash returns the SHA-1 of the bencoded info dict which identifies the torrent
+// in announces and peer handshakes.
+// When the torrent was not decoded from a file (no "RawInfo") the canonical encoding of "Info" is hashed.
+func (t TorrentFile) InfoHash() [20]byte {
+	if len(t.RawInfo) != 0 {
+		return sha1.Sum(t.RawInfo)
+	}
+
+	// encoding TorrentInfo cannot fail since all of its fields are supported
+	info, _ := bencode.Marshal(t.Info)
+	return sha1.Sum(info)
+}
+
+// MarshalBencode writes RawInfo as is when it's set so a decoded torrent keeps its info hash,
+// re-encoding "Info" would drop things like a "private" of 0 or unsorted keys.
+// A "url-list" that was a single string is written back as a string.
+func (t TorrentFile) MarshalBencode() ([]byte, error) {
+	// torrentFile doesn't have this method so Marshal uses the struct tags
+	type torrentFile TorrentFile
+	data, err := bencode.Marshal(torrentFile(t))
+	if err != nil {
+		return nil, err
+	}
+
+	urlListString := t.urlListString && len(t.URLList) == 1
+	if len(t.RawInfo) == 0 && !urlListString {
+		return data, nil
+	}
+
+	var dict map[string]bencode.RawMessage
+	if err := bencode.Unmarshal(data, &dict); err != nil {
+		return nil, err
+	}
+	if len(t.RawInfo) != 0 {
+		dict["info"] = t.RawInfo
+	}
+	if urlListString {
+		dict["url-list"], _ = bencode.Marshal(t.URLList[0])
+	}
+
+	return bencode.Marshal(dict)
 }
 
 func (t TorrentFile) String() string {
@@ -35,12 +115,13 @@ func (t TorrentFile) String() string {
   [encoding]: %s
   [info]: {
     [length]: %d
+    [files]: %d
     [name]: %s
     [pieces length]: %d
     [pieces]: [...]
   }
-  `, t.Announce, t.AnnounceList, t.CreatedBy, time.Unix(int64(t.CreationDate), 0),
-		t.Encoding, t.Info.Length, t.Info.Name, t.Info.PieceLength)
+  `, t.Announce, t.AnnounceList, t.CreatedBy, time.Unix(t.CreationDate, 0),
+		t.Encoding, t.Info.TotalLength(), len(t.Info.FileEntries()), t.Info.Name, t.Info.PieceLength)
 }
 
 // This is type alias that does not declare a new type
@@ -54,197 +135,66 @@ func DecodeTorrentFile(filename string) (*TorrentFile, error) {
 		return nil, err
 	}
 
-	dict, err := Decode(string(b))
-	if err != nil {
-		return nil, err
-	}
-
-	var t TorrentFile
-	err = utils.MapToStruct(dict, &t)
-	if err != nil {
-		return nil, err
-	}
-
-	return &t, nil
+	return ParseTorrentFile(b)
 }
 
-func Decode(bencode string) (BencodeDict, error) {
-	if len(bencode) == 0 {
-		return nil, errors.New("Expected a bencode string got an empty string instead")
-	}
-
-	i := 0
-	dict, err := consumeDict(bencode, &i)
+// ParseTorrentFile decodes the content of a .torrent file.
+func ParseTorrentFile(data []byte) (*TorrentFile, error) {
+	t, err := ParseUnvalidated(data)
 	if err != nil {
 		return nil, err
 	}
 
-	return dict, nil
-}
-
-/*
-* PLEASE NOTE ALL "consumeXXX" FUNC WILL POSITION "pos" AFTER THE PARSED VALUE
- */
-
-func consumeDict(bencode string, pos *int) (BencodeDict, error) {
-	if *pos >= len(bencode) {
-		return nil, errors.New(fmt.Sprintf("Position='%d' is greater than bencode length='%d'", *pos, len(bencode)))
-	}
-
-	if bencode[*pos] != 'd' {
-		return nil, errors.New(fmt.Sprintf("Expected a dict start at pos %d got %s instead", *pos, string(bencode[*pos])))
-	}
-
-	dict := make(BencodeDict)
-
-	*pos++ // skip the d
-	for *pos < len(bencode) && bencode[*pos] != 'e' {
-		key, err := consumeString(bencode, pos)
-		if err != nil {
+	if t.Info.HasV1() {
+		if err := t.Info.ValidatePieces(); err != nil {
 			return nil, err
 		}
-
-		var val any
-		switch bencode[*pos] {
-		case 'd':
-			d, err := consumeDict(bencode, pos)
-			if err != nil {
-				return nil, err
-			}
-			val = d
-
-		case 'l':
-			l, err := consumeList(bencode, pos)
-			if err != nil {
-				return nil, err
-			}
-			val = l
-
-		case 'i':
-			num, err := consumeInt(bencode, pos)
-			if err != nil {
-				return nil, err
-			}
-			val = num
-
-		default:
-			str, err := consumeString(bencode, pos)
-			if err != nil {
-				return nil, err
-			}
-
-			val = str
-		}
-
-		dict[key] = val
-	}
-
-	if *pos < len(bencode) && bencode[*pos] == 'e' {
-		*pos += 1
-		return dict, nil
 	}
-
-	return nil, errors.New(fmt.Sprintf("Expected dict to end with 'e' at position '%d'", *pos))
-}
-
-func consumeList(bencode string, pos *int) ([]any, error) {
-	if *pos >= len(bencode) {
-		return nil, errors.New(fmt.Sprintf("Position='%d' is greater than bencode length='%d'", *pos, len(bencode)))
-	}
-
-	if bencode[*pos] != 'l' {
-		return nil, errors.New(fmt.Sprintf("Expected a list start at pos %d got %s instead", *pos, string(bencode[*pos])))
-	}
-
-	arr := make([]any, 0)
-	*pos++ // skip the 'l'
-	for *pos < len(bencode) && bencode[*pos] != 'e' {
-		var val any
-		switch bencode[*pos] {
-		case 'i':
-			n, err := consumeInt(bencode, pos)
-			if err != nil {
-				return nil, err
-			}
-			val = n
-
-		case 'l':
-			l, err := consumeList(bencode, pos)
-			if err != nil {
-				return nil, err
-			}
-			val = l
-
-		case 'd':
-			d, err := consumeDict(bencode, pos)
-			if err != nil {
-				return nil, err
-			}
-			val = d
-
-		default:
-			s, err := consumeString(bencode, pos)
-			if err != nil {
-				return nil, err
-			}
-
-			val = s
+	if t.Info.HasV2() {
+		// every other key of the tree is a name, only a file right at the root has none
+		if t.Info.FileTree.File != nil {
+			return nil, errors.New("File tree has a file without a name")
+		}
+		if err := t.VerifyPieceLayers(); err != nil {
+			return nil, err
 		}
-
-		arr = append(arr, val)
-	}
-
-	if *pos < len(bencode) && bencode[*pos] == 'e' {
-		*pos += 1
-		return arr, nil
 	}
 
-	return nil, errors.New(fmt.Sprintf("Expected list to end with 'e' at position '%d'", *pos))
+	return t, nil
 }
 
-func consumeString(bencode string, pos *int) (string, error) {
-	if *pos >= len(bencode) {
-		return "", errors.New(fmt.Sprintf("Position='%d' is greater than bencode length='%d'", *pos, len(bencode)))
+// ParseUnvalidated decodes a .torrent file without the piece checks of ParseTorrentFile,
+// it's meant for tools such as Validate that want to report every problem of a torrent.
+func ParseUnvalidated(data []byte) (*TorrentFile, error) {
+	root, err := bencode.Parse(data)
+	if err != nil {
+		return nil, err
 	}
 
-	sep := strings.IndexByte(bencode[*pos:], ':')
-	if sep == -1 {
-		return "", errors.New(fmt.Sprintf("Expected a separator around index %d", *pos))
+	info := root.Get("info")
+	if info.Kind() != bencode.Dict {
+		return nil, errors.New("Torrent file is missing the info dict")
 	}
-	sep += *pos
 
-	strLen, err := strconv.Atoi(bencode[*pos:sep])
-	if err != nil || strLen <= 0 {
-		return "", errors.New(fmt.Sprintf("Expected str len to be a positive number got %s instead at pos %d", bencode[*pos:sep], *pos))
+	var t TorrentFile
+	if err := root.Decode(&t); err != nil {
+		return nil, err
 	}
+	t.RawInfo = bytes.Clone(info.Raw())
+	t.urlListString = root.Get("url-list").Kind() == bencode.String
 
-	str := bencode[sep+1 : sep+1+strLen]
-	*pos += (sep - *pos) + strLen + 1
-	return str, nil
+	return &t, nil
 }
 
-func consumeInt(bencode string, pos *int) (int, error) {
-	if *pos >= len(bencode) {
-		return 0, errors.New(fmt.Sprintf("Position='%d' is greater than bencode length='%d'", *pos, len(bencode)))
-	}
-
-	if bencode[*pos] != 'i' {
-		return 0, errors.New(fmt.Sprintf("Expected start of int got %s at index %d instead", string(bencode[*pos]), *pos))
-	}
-
-	intEnding := strings.IndexByte(bencode[*pos:], 'e')
-	if intEnding == -1 {
-		return 0, errors.New(fmt.Sprintf("Int with no delimiter found at %d", *pos))
+func Decode(data string) (BencodeDict, error) {
+	if len(data) == 0 {
+		return nil, errors.New("Expected a bencode string got an empty string instead")
 	}
-	intEnding += *pos
 
-	num, err := strconv.Atoi(bencode[*pos+1 : intEnding])
-	if err != nil {
-		return 0, errors.New(fmt.Sprintf("Cannot convert int at %d got '%s' from (%d, %d) instead", *pos, bencode[*pos+1:intEnding], *pos+1, intEnding))
+	var dict BencodeDict
+	if err := bencode.Unmarshal([]byte(data), &dict); err != nil {
+		return nil, err
 	}
 
-	*pos = intEnding + 1
-	return num, nil
+	return dict, nil
 }
-
-/* END OF consumeXXX functions */
@@ -0,0 +1,48 @@
+package decoder
+
+import (
+	"crypto/sha1"
+	"errors"
+	"gotorrent/bencode"
+)
+
+// TorrentDiff is the result of comparing two .torrent files.
+type TorrentDiff struct {
+	Changes     []bencode.Change
+	OldInfoHash [20]byte
+	NewInfoHash [20]byte
+}
+
+// InfoHashChanged reports whether the two files describe different torrents,
+// as opposed to only having different trackers, comments...
+func (d TorrentDiff) InfoHashChanged() bool {
+	return d.OldInfoHash != d.NewInfoHash
+}
+
+// DiffTorrentFiles compares the content of two .torrent files key by key (see bencode.Diff).
+// The info hashes are computed from the raw info dicts, the files are not checked any further
+// so a torrent with broken pieces can still be compared.
+func DiffTorrentFiles(a, b []byte) (*TorrentDiff, error) {
+	oldRoot, err := bencode.Parse(a)
+	if err != nil {
+		return nil, err
+	}
+	newRoot, err := bencode.Parse(b)
+	if err != nil {
+		return nil, err
+	}
+
+	oldInfo, newInfo := oldRoot.Get("info"), newRoot.Get("info")
+	if oldInfo.Kind() != bencode.Dict {
+		return nil, errors.New("The first file is missing the info dict")
+	}
+	if newInfo.Kind() != bencode.Dict {
+		return nil, errors.New("The second file is missing the info dict")
+	}
+
+	return &TorrentDiff{
+		Changes:     bencode.Diff(oldRoot, newRoot),
+		OldInfoHash: sha1.Sum(oldInfo.Raw()),
+		NewInfoHash: sha1.Sum(newInfo.Raw()),
+	}, nil
+}
@@ -0,0 +1,93 @@
+package decoder
+
+import (
+	"gotorrent/bencode"
+	"os"
+	"testing"
+)
+
+func TestDiffTorrentFiles(t *testing.T) {
+	data, err := os.ReadFile("./files/test.torrent")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edit := func(fn func(d *bencode.OrderedDict)) []byte {
+		var d bencode.OrderedDict
+		if err := bencode.Unmarshal(data, &d); err != nil {
+			t.Fatal(err)
+		}
+		fn(&d)
+
+		res, err := bencode.Marshal(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	tests := []struct {
+		name            string
+		edited          []byte
+		expected        []string
+		infoHashChanged bool
+	}{
+		{"same file", data, []string{}, false},
+		{
+			"new tracker",
+			edit(func(d *bencode.OrderedDict) {
+				d.Set("announce", "http://new")
+				d.Delete("comment")
+			}),
+			[]string{`~ announce: "https://torrent.ubuntu.com/announce" -> "http://new"`, `- comment: "Ubuntu CD releases.ubuntu.com"`},
+			false,
+		},
+		{
+			"renamed",
+			edit(func(d *bencode.OrderedDict) {
+				info, _ := d.Get("info")
+				info.(*bencode.OrderedDict).Set("name", "renamed.iso")
+			}),
+			[]string{`~ info.name: "ubuntu-24.04.1-desktop-amd64.iso" -> "renamed.iso"`},
+			true,
+		},
+	}
+
+	for _, test := range tests {
+		diff, err := DiffTorrentFiles(data, test.edited)
+		if err != nil {
+			t.Fatalf("%s: expected no error got %s instead", test.name, err)
+		}
+
+		res := make([]string, 0)
+		for _, change := range diff.Changes {
+			res = append(res, change.String())
+		}
+
+		if len(res) != len(test.expected) {
+			t.Fatalf("%s: expected %q got %q instead", test.name, test.expected, res)
+		}
+		for i := range res {
+			if res[i] != test.expected[i] {
+				t.Errorf("%s: expected %s got %s instead", test.name, test.expected[i], res[i])
+			}
+		}
+
+		if diff.InfoHashChanged() != test.infoHashChanged {
+			t.Errorf("%s: expected info hash changed to be %t", test.name, test.infoHashChanged)
+		}
+	}
+
+	// the pieces don't have to be valid to compare the info hashes
+	broken := edit(func(d *bencode.OrderedDict) {
+		info, _ := d.Get("info")
+		info.(*bencode.OrderedDict).Set("pieces", "short")
+	})
+	if diff, err := DiffTorrentFiles(data, broken); err != nil || !diff.InfoHashChanged() {
+		t.Errorf("expected a changed info hash for broken pieces got %v instead", err)
+	}
+
+	if _, err := DiffTorrentFiles(data, []byte("de")); err == nil {
+		t.Errorf("expected an error when a file is not a torrent")
+	}
+}
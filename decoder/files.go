@@ -0,0 +1,165 @@
+package decoder
+
+import (
+	"errors"
+	"fmt"
+	"gotorrent/bencode"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// FileEntry is one element of the "files" list of a multi-file torrent.
+type FileEntry struct {
+	Length int64 `bencode:"length"`
+	// Path is made of the directories & file name relative to the torrent's "name" directory
+	Path   []string `bencode:"path"`
+	Md5sum string   `bencode:"md5sum,omitempty"`
+	// Attr holds the BEP 47 attributes, any of 'p' (padding), 'x' (executable), 'h' (hidden) and 'l' (symlink)
+	Attr        string   `bencode:"attr,omitempty"`
+	SymlinkPath []string `bencode:"symlink path,omitempty"`
+	// Extra keeps unknown keys such as "sha1" or "ed2k"
+	Extra map[string]bencode.RawMessage `bencode:",extra"`
+}
+
+func (f FileEntry) IsPadding() bool {
+	return strings.ContainsRune(f.Attr, 'p')
+}
+
+func (f FileEntry) IsExecutable() bool {
+	return strings.ContainsRune(f.Attr, 'x')
+}
+
+func (f FileEntry) IsHidden() bool {
+	return strings.ContainsRune(f.Attr, 'h')
+}
+
+func (f FileEntry) IsSymlink() bool {
+	return strings.ContainsRune(f.Attr, 'l')
+}
+
+// IsMultiFile reports whether the torrent is made of a "files" list instead of a single file of "length" bytes.
+func (i TorrentInfo) IsMultiFile() bool {
+	return len(i.Files) > 0
+}
+
+// FileEntries returns the files of the torrent in the order their data is laid out in the pieces.
+// A single file torrent gives a single entry with an empty Path, which stands for the file named "name".
+func (i TorrentInfo) FileEntries() []FileEntry {
+	if i.IsMultiFile() {
+		return i.Files
+	}
+
+	return []FileEntry{{Length: i.Length, Path: []string{}}}
+}
+
+// TotalLength returns the size of all the files (padding files included).
+func (i TorrentInfo) TotalLength() int64 {
+	if !i.IsMultiFile() {
+		return i.Length
+	}
+
+	var total int64
+	for _, f := range i.Files {
+		total += f.Length
+	}
+	return total
+}
+
+// FileOffsets returns the byte offset within the torrent's data of each entry of FileEntries.
+func (i TorrentInfo) FileOffsets() []int64 {
+	files := i.FileEntries()
+	offsets := make([]int64, len(files))
+
+	var offset int64
+	for idx, f := range files {
+		offsets[idx] = offset
+		offset += f.Length
+	}
+	return offsets
+}
+
+// DiskPath returns where f (one of FileEntries) is stored when the torrent is saved under root:
+// root/name for a single file torrent and root/name/path... for a multi-file one.
+// Path components are checked so a malicious torrent cannot write outside of root.
+func (i TorrentInfo) DiskPath(root string, f FileEntry) (string, error) {
+	if i.IsMultiFile() && len(f.Path) == 0 {
+		return "", errors.New("Expected file path to have at least one component")
+	}
+
+	return SafeJoin(root, append([]string{i.Name}, f.Path...)...)
+}
+
+// DiskPathV2 is DiskPath for the files of a v2 torrent, their path already starts
+// with the torrent's name for single file torrents so the name is only added for multi-file ones.
+func (i TorrentInfo) DiskPathV2(root string, f FileEntryV2) (string, error) {
+	if len(f.Path) == 0 {
+		return "", errors.New("Expected file path to have at least one component")
+	}
+
+	files := i.FilesV2()
+	if len(files) == 1 && len(files[0].Path) == 1 && files[0].Path[0] == i.Name {
+		return SafeJoin(root, f.Path...)
+	}
+	return SafeJoin(root, append([]string{i.Name}, f.Path...)...)
+}
+
+// SafeJoin joins path components coming from a torrent to root,
+// it fails if any of them could make the result point outside of root (see CheckPathComponent).
+// Every path built out of a torrent's metadata must go through it before touching the disk.
+func SafeJoin(root string, parts ...string) (string, error) {
+	for _, part := range parts {
+		if err := CheckPathComponent(part); err != nil {
+			return "", err
+		}
+		if runtime.GOOS == "windows" && IsReservedName(part) {
+			return "", errors.New(fmt.Sprintf("Path component '%s' is a reserved name", part))
+		}
+	}
+
+	path := filepath.Join(append([]string{root}, parts...)...)
+
+	// the checks above should already prevent it but better safe than sorry
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(rel) {
+		return "", errors.New(fmt.Sprintf("Path '%s' is outside of '%s'", path, root))
+	}
+
+	return path, nil
+}
+
+// CheckPathComponent rejects the file & directory names that cannot be safely used on disk:
+// empty names, "." and "..", names with separators (which covers absolute paths) and NUL bytes.
+func CheckPathComponent(part string) error {
+	if part == "" || part == "." || part == ".." {
+		return errors.New(fmt.Sprintf("Invalid path component '%s'", part))
+	}
+
+	if strings.ContainsAny(part, `/\`) || strings.ContainsRune(part, 0) {
+		return errors.New(fmt.Sprintf("Path component '%s' contains a separator", part))
+	}
+
+	// "C:" would make filepath.Join on windows start a new volume
+	if filepath.VolumeName(part) != "" {
+		return errors.New(fmt.Sprintf("Path component '%s' is a volume name", part))
+	}
+
+	return nil
+}
+
+// IsReservedName reports whether name cannot be used as a file name on windows:
+// device names such as CON or COM1 (with any extension) and names ending with a dot or a space.
+func IsReservedName(name string) bool {
+	if strings.HasSuffix(name, ".") || strings.HasSuffix(name, " ") {
+		return true
+	}
+
+	base, _, _ := strings.Cut(name, ".")
+	switch strings.ToUpper(strings.TrimRight(base, " ")) {
+	case "CON", "PRN", "AUX", "NUL",
+		"COM1", "COM2", "COM3", "COM4", "COM5", "COM6", "COM7", "COM8", "COM9",
+		"LPT1", "LPT2", "LPT3", "LPT4", "LPT5", "LPT6", "LPT7", "LPT8", "LPT9":
+		return true
+	}
+	return false
+}
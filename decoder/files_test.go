@@ -0,0 +1,184 @@
+package decoder
+
+import (
+	"crypto/sha1"
+	"gotorrent/bencode"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// multiFileTorrent builds a torrent made of files with dummy piece hashes.
+func multiFileTorrent(t *testing.T, pieceLength int, files ...FileEntry) []byte {
+	var total int64
+	for _, f := range files {
+		total += f.Length
+	}
+	numPieces := (total + int64(pieceLength) - 1) / int64(pieceLength)
+
+	data, err := bencode.Marshal(map[string]any{
+		"announce": "http://tracker",
+		"info": TorrentInfo{
+			Files:       files,
+			Name:        "dir",
+			PieceLength: pieceLength,
+			Pieces:      make(PieceHashes, numPieces),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestMultiFileTorrent(t *testing.T) {
+	expectedFiles := []FileEntry{
+		{Length: 10, Path: []string{"a.txt"}},
+		{Length: 5, Path: []string{"sub", "b.bin"}},
+		{Length: 15, Path: []string{"sub", "c"}},
+	}
+	data := multiFileTorrent(t, 16, expectedFiles...)
+
+	parsed, err := ParseTorrentFile(data)
+	if err != nil {
+		t.Fatalf("expected no error got %s instead", err)
+	}
+
+	if !parsed.Info.IsMultiFile() {
+		t.Fatalf("expected a multi-file torrent")
+	}
+
+	if !reflect.DeepEqual(parsed.Info.FileEntries(), expectedFiles) {
+		t.Errorf("expected files %+v got %+v instead", expectedFiles, parsed.Info.FileEntries())
+	}
+
+	if parsed.Info.TotalLength() != 30 {
+		t.Errorf("expected a total length of 30 got %d instead", parsed.Info.TotalLength())
+	}
+
+	if offsets := parsed.Info.FileOffsets(); !reflect.DeepEqual(offsets, []int64{0, 10, 15}) {
+		t.Errorf("expected offsets [0 10 15] got %v instead", offsets)
+	}
+
+	path, err := parsed.Info.DiskPath("/downloads", expectedFiles[1])
+	if err != nil || path != filepath.Join("/downloads", "dir", "sub", "b.bin") {
+		t.Errorf("unexpected disk path %s (%v)", path, err)
+	}
+
+	// re-encoding the info dict must not add a "length" key
+	parsed.RawInfo = nil
+	if parsed.InfoHash() != sha1Of(t, data) {
+		t.Errorf("expected the info hash of the re-encoded info dict to match the file")
+	}
+}
+
+func sha1Of(t *testing.T, data []byte) [20]byte {
+	root, err := bencode.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sha1.Sum(root.Get("info").Raw())
+}
+
+func TestSingleFileEntries(t *testing.T) {
+	parsed, err := DecodeTorrentFile("./files/test.torrent")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if parsed.Info.IsMultiFile() {
+		t.Errorf("expected a single file torrent")
+	}
+
+	files := parsed.Info.FileEntries()
+	if len(files) != 1 || files[0].Length != 6203355136 || parsed.Info.TotalLength() != 6203355136 {
+		t.Errorf("unexpected files %+v", files)
+	}
+
+	path, err := parsed.Info.DiskPath("root", files[0])
+	if err != nil || path != filepath.Join("root", "ubuntu-24.04.1-desktop-amd64.iso") {
+		t.Errorf("unexpected disk path %s (%v)", path, err)
+	}
+}
+
+func TestDiskPathErrors(t *testing.T) {
+	info := TorrentInfo{Name: "dir", Files: []FileEntry{{Length: 1, Path: []string{"a"}}}}
+
+	tests := [][]string{
+		{},
+		{"..", "etc", "passwd"},
+		{"a", ".."},
+		{"a/../../b"},
+		{""},
+		{`a\b`},
+	}
+
+	for _, test := range tests {
+		if _, err := info.DiskPath("root", FileEntry{Path: test}); err == nil {
+			t.Errorf("expected an error for path %q", test)
+		}
+	}
+
+	if _, err := (TorrentInfo{Name: ".."}).DiskPath("root", FileEntry{}); err == nil {
+		t.Errorf("expected an error for a '..' name")
+	}
+}
+
+func TestDiskPathV2(t *testing.T) {
+	torrent, _ := v2Torrent(t)
+	files := torrent.Info.FilesV2()
+
+	path, err := torrent.Info.DiskPathV2("root", files[0])
+	expected := filepath.Join(append([]string{"root", torrent.Info.Name}, files[0].Path...)...)
+	if err != nil || path != expected {
+		t.Errorf("expected %s got %s (%v) instead", expected, path, err)
+	}
+
+	// a single file torrent has the file named after the torrent
+	single := TorrentInfo{Name: "file.iso", MetaVersion: 2, FileTree: &FileTree{Children: map[string]*FileTree{
+		"file.iso": {File: &FileTreeEntry{Length: 1}},
+	}}}
+	path, err = single.DiskPathV2("root", single.FilesV2()[0])
+	if err != nil || path != filepath.Join("root", "file.iso") {
+		t.Errorf("unexpected disk path %s (%v)", path, err)
+	}
+
+	if _, err := torrent.Info.DiskPathV2("root", FileEntryV2{Path: []string{".."}}); err == nil {
+		t.Errorf("expected an error for a '..' path")
+	}
+}
+
+func TestIsReservedName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected bool
+	}{
+		{"CON", true},
+		{"con.txt", true},
+		{"Com1.tar.gz", true},
+		{"lpt9", true},
+		{"name.", true},
+		{"name ", true},
+		{"console", false},
+		{"com10", false},
+		{"file.txt", false},
+		{".hidden", false},
+	}
+
+	for _, test := range tests {
+		if res := IsReservedName(test.name); res != test.expected {
+			t.Errorf("expected %v for %s got %v instead", test.expected, test.name, res)
+		}
+	}
+}
+
+func TestFileAttributes(t *testing.T) {
+	f := FileEntry{Attr: "xh"}
+	if !f.IsExecutable() || !f.IsHidden() || f.IsPadding() || f.IsSymlink() {
+		t.Errorf("unexpected attributes for %s", f.Attr)
+	}
+
+	if !(FileEntry{Attr: "p"}).IsPadding() || !(FileEntry{Attr: "l"}).IsSymlink() {
+		t.Errorf("expected 'p' to be padding and 'l' a symlink")
+	}
+}
@@ -0,0 +1,137 @@
+package decoder
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+)
+
+// BlockSize is the size of the leaves of the v2 (BEP 52) merkle trees.
+const BlockSize = 16 * 1024
+
+// FileHashV2 is the result of hashing the content of a file for a v2 torrent.
+type FileHashV2 struct {
+	Length     int64
+	PiecesRoot [32]byte // zero for empty files which don't have one
+	// PieceLayer holds the concatenated hashes of each piece, it's only set
+	// for files larger than one piece since those are the only ones listed in "piece layers"
+	PieceLayer []byte
+}
+
+// HashFileV2 reads a whole file and builds its merkle tree: each 16KiB block is hashed with SHA-256,
+// then pairs of hashes are hashed together up to the root, the missing leaves being all zeros.
+func HashFileV2(r io.Reader, pieceLength int) (FileHashV2, error) {
+	if err := checkPieceLengthV2(pieceLength); err != nil {
+		return FileHashV2{}, err
+	}
+
+	var res FileHashV2
+	blocks := make([][32]byte, 0)
+	buf := make([]byte, BlockSize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			blocks = append(blocks, sha256.Sum256(buf[:n]))
+			res.Length += int64(n)
+		}
+
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return FileHashV2{}, err
+		}
+	}
+
+	if res.Length == 0 {
+		return res, nil
+	}
+
+	blocksPerPiece := pieceLength / BlockSize
+	if len(blocks) <= blocksPerPiece {
+		res.PiecesRoot = merkleRoot(blocks, nextPowerOfTwo(len(blocks)), [32]byte{})
+		return res, nil
+	}
+
+	layer := make([][32]byte, 0, (len(blocks)+blocksPerPiece-1)/blocksPerPiece)
+	for start := 0; start < len(blocks); start += blocksPerPiece {
+		end := min(start+blocksPerPiece, len(blocks))
+		layer = append(layer, merkleRoot(blocks[start:end], blocksPerPiece, [32]byte{}))
+	}
+
+	for _, h := range layer {
+		res.PieceLayer = append(res.PieceLayer, h[:]...)
+	}
+	res.PiecesRoot = merkleRoot(layer, nextPowerOfTwo(len(layer)), padHash(pieceLength))
+
+	return res, nil
+}
+
+// rootFromPieceLayer computes the pieces root of a file from its piece layer.
+func rootFromPieceLayer(layer []byte, pieceLength int) [32]byte {
+	hashes := make([][32]byte, len(layer)/32)
+	for i := range hashes {
+		copy(hashes[i][:], layer[i*32:])
+	}
+
+	return merkleRoot(hashes, nextPowerOfTwo(len(hashes)), padHash(pieceLength))
+}
+
+// merkleRoot hashes a layer of width nodes (a power of two) up to the root,
+// the nodes past the end of layer are set to pad.
+func merkleRoot(layer [][32]byte, width int, pad [32]byte) [32]byte {
+	nodes := make([][32]byte, width)
+	copy(nodes, layer)
+	for i := len(layer); i < width; i++ {
+		nodes[i] = pad
+	}
+
+	for len(nodes) > 1 {
+		for i := 0; i < len(nodes)/2; i++ {
+			nodes[i] = hashPair(nodes[2*i], nodes[2*i+1])
+		}
+		nodes = nodes[:len(nodes)/2]
+		// the missing nodes of the next layer are the hash of two missing nodes
+		pad = hashPair(pad, pad)
+	}
+
+	return nodes[0]
+}
+
+// padHash returns the root of a subtree made of one piece of zero leaves,
+// which is what the piece layer is padded with.
+func padHash(pieceLength int) [32]byte {
+	var h [32]byte
+	for n := pieceLength / BlockSize; n > 1; n /= 2 {
+		h = hashPair(h, h)
+	}
+	return h
+}
+
+func hashPair(a, b [32]byte) [32]byte {
+	var buf [64]byte
+	copy(buf[:32], a[:])
+	copy(buf[32:], b[:])
+	return sha256.Sum256(buf[:])
+}
+
+func nextPowerOfTwo(n int) int {
+	if n <= 1 {
+		return 1
+	}
+	return 1 << bits.Len(uint(n-1))
+}
+
+// checkPieceLengthV2 enforces BEP 52 piece lengths: a power of two of at least 16KiB,
+// and no more than MaxPieceLength.
+func checkPieceLengthV2(pieceLength int) error {
+	if pieceLength < BlockSize || pieceLength&(pieceLength-1) != 0 {
+		return errors.New(fmt.Sprintf("Expected piece length to be a power of two of at least %d got %d instead", BlockSize, pieceLength))
+	}
+	if pieceLength > MaxPieceLength {
+		return errors.New(fmt.Sprintf("Expected piece length to be at most %d got %d instead", MaxPieceLength, pieceLength))
+	}
+	return nil
+}
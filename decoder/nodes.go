@@ -0,0 +1,47 @@
+package decoder
+
+import (
+	"errors"
+	"fmt"
+	"gotorrent/bencode"
+	"net"
+	"strconv"
+)
+
+// Node is a DHT node from the "nodes" list of trackerless torrents (BEP 5),
+// it's encoded as a [host, port] list.
+type Node struct {
+	Host string
+	Port int
+}
+
+func (n Node) String() string {
+	return net.JoinHostPort(n.Host, strconv.Itoa(n.Port))
+}
+
+func (n Node) MarshalBencode() ([]byte, error) {
+	return bencode.Marshal([]any{n.Host, n.Port})
+}
+
+func (n *Node) UnmarshalBencode(data []byte) error {
+	var node []bencode.RawMessage
+	if err := bencode.Unmarshal(data, &node); err != nil {
+		return err
+	}
+
+	if len(node) != 2 {
+		return errors.New(fmt.Sprintf("Expected a DHT node to be a [host, port] list got %d elements instead", len(node)))
+	}
+
+	if err := bencode.Unmarshal(node[0], &n.Host); err != nil {
+		return err
+	}
+	if err := bencode.Unmarshal(node[1], &n.Port); err != nil {
+		return err
+	}
+
+	if n.Port < 0 || n.Port > 65535 {
+		return errors.New(fmt.Sprintf("Invalid DHT node port %d", n.Port))
+	}
+	return nil
+}
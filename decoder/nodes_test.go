@@ -0,0 +1,36 @@
+package decoder
+
+import (
+	"gotorrent/bencode"
+	"testing"
+)
+
+func TestNode(t *testing.T) {
+	var node Node
+	if err := bencode.Unmarshal([]byte("l11:example.comi80ee"), &node); err != nil {
+		t.Fatalf("expected no error got %s instead", err)
+	}
+	if node.String() != "example.com:80" {
+		t.Errorf("expected example.com:80 got %s instead", node)
+	}
+
+	if b, _ := bencode.Marshal(node); string(b) != "l11:example.comi80ee" {
+		t.Errorf("expected the node to be encoded as a list got %s instead", b)
+	}
+
+	tests := []string{
+		"11:example.com",
+		"l11:example.come",
+		"l11:example.comi80ei1ee",
+		"li80e11:example.come",
+		"l11:example.comi65536ee",
+		"l11:example.comi-1ee",
+	}
+
+	for _, test := range tests {
+		var res Node
+		if err := bencode.Unmarshal([]byte(test), &res); err == nil {
+			t.Errorf("expected an error for %s", test)
+		}
+	}
+}
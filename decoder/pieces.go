@@ -0,0 +1,157 @@
+package decoder
+
+import (
+	"errors"
+	"fmt"
+	"gotorrent/bencode"
+	"sort"
+)
+
+// PieceHashes is the "pieces" string of a torrent split into the SHA-1 of each piece.
+// On the wire it's a single string made of the concatenated 20 bytes hashes.
+type PieceHashes [][20]byte
+
+func (p PieceHashes) MarshalBencode() ([]byte, error) {
+	pieces := make([]byte, 0, len(p)*20)
+	for _, h := range p {
+		pieces = append(pieces, h[:]...)
+	}
+
+	return bencode.Marshal(pieces)
+}
+
+func (p *PieceHashes) UnmarshalBencode(data []byte) error {
+	var pieces []byte
+	if err := bencode.Unmarshal(data, &pieces); err != nil {
+		return err
+	}
+
+	hashes, err := splitPieces(pieces)
+	if err != nil {
+		return err
+	}
+
+	*p = hashes
+	return nil
+}
+
+func splitPieces(pieces []byte) (PieceHashes, error) {
+	if len(pieces)%20 != 0 {
+		return nil, errors.New(fmt.Sprintf("Expected pieces length to be a multiple of 20 got %d instead", len(pieces)))
+	}
+
+	hashes := make(PieceHashes, len(pieces)/20)
+	for i := range hashes {
+		copy(hashes[i][:], pieces[i*20:])
+	}
+
+	return hashes, nil
+}
+
+// NumPieces returns how many pieces the torrent is split into.
+func (i TorrentInfo) NumPieces() int {
+	return len(i.Pieces)
+}
+
+// PieceHash returns the SHA-1 of the i-th piece, it panics when idx is out of range just like indexing a slice.
+func (i TorrentInfo) PieceHash(idx int) [20]byte {
+	return i.Pieces[idx]
+}
+
+// PieceSize returns the size of the i-th piece, every piece is "piece length" bytes long
+// except the last one which holds whatever is left.
+func (i TorrentInfo) PieceSize(idx int) int64 {
+	if idx < 0 || idx >= i.NumPieces() {
+		return 0
+	}
+
+	if idx == i.NumPieces()-1 {
+		return i.TotalLength() - int64(idx)*int64(i.PieceLength)
+	}
+	return int64(i.PieceLength)
+}
+
+// MaxPieceLength is the largest piece length we accept, pieces are read whole in memory
+// to be hashed and real torrents stay way below it (16MiB is already a lot).
+const MaxPieceLength = 256 * 1024 * 1024
+
+// ValidatePieces checks that there is exactly one hash per "piece length" bytes of data,
+// a "pieces" that's not made of 20 bytes hashes is already rejected when decoding.
+// Files with a negative length are rejected as well since they'd throw off the offsets of the pieces.
+func (i TorrentInfo) ValidatePieces() error {
+	if i.PieceLength <= 0 || i.PieceLength > MaxPieceLength {
+		return errors.New(fmt.Sprintf("Expected a piece length between 1 and %d got %d instead", MaxPieceLength, i.PieceLength))
+	}
+
+	for idx, f := range i.FileEntries() {
+		if f.Length < 0 {
+			return errors.New(fmt.Sprintf("Expected a positive length for file %d got %d instead", idx, f.Length))
+		}
+	}
+
+	total := i.TotalLength()
+	expected := (total + int64(i.PieceLength) - 1) / int64(i.PieceLength)
+	if int64(i.NumPieces()) != expected {
+		return errors.New(fmt.Sprintf("Expected %d pieces for %d bytes with a piece length of %d got %d instead",
+			expected, total, i.PieceLength, i.NumPieces()))
+	}
+
+	return nil
+}
+
+// FileSegment is the part of a file that falls inside a piece.
+type FileSegment struct {
+	File   int   // index in FileEntries
+	Offset int64 // where the segment starts within the file
+	Length int64
+}
+
+// PieceSegments returns the parts of the files that make the i-th piece in order.
+// Empty files are never part of a piece.
+func (i TorrentInfo) PieceSegments(idx int) []FileSegment {
+	segments := make([]FileSegment, 0)
+	if idx < 0 || idx >= i.NumPieces() {
+		return segments
+	}
+
+	files := i.FileEntries()
+	offsets := i.FileOffsets()
+	start := int64(idx) * int64(i.PieceLength)
+	end := start + i.PieceSize(idx)
+
+	// first file ending after the start of the piece
+	f := sort.Search(len(files), func(n int) bool {
+		return offsets[n]+files[n].Length > start
+	})
+	for ; f < len(files) && offsets[f] < end; f++ {
+		if files[f].Length == 0 {
+			continue
+		}
+
+		segStart := max(start, offsets[f])
+		segEnd := min(end, offsets[f]+files[f].Length)
+		segments = append(segments, FileSegment{File: f, Offset: segStart - offsets[f], Length: segEnd - segStart})
+	}
+
+	return segments
+}
+
+// FilePieces returns the range of pieces [first, end) holding the data of the file at index idx
+// in FileEntries. The first and last pieces may be shared with the neighbouring files,
+// the range is empty for empty files.
+func (i TorrentInfo) FilePieces(idx int) (first, end int) {
+	files := i.FileEntries()
+	if idx < 0 || idx >= len(files) || i.PieceLength <= 0 {
+		return 0, 0
+	}
+
+	offset := i.FileOffsets()[idx]
+	pieceLength := int64(i.PieceLength)
+	first = int(offset / pieceLength)
+	if files[idx].Length == 0 {
+		return first, first
+	}
+
+	end = int((offset + files[idx].Length + pieceLength - 1) / pieceLength)
+	return first, end
+}
@@ -0,0 +1,134 @@
+package decoder
+
+import (
+	"gotorrent/bencode"
+	"reflect"
+	"testing"
+)
+
+func TestPieceAccessors(t *testing.T) {
+	parsed, err := DecodeTorrentFile("./files/test.torrent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := parsed.Info
+
+	if info.NumPieces() != 23664 {
+		t.Errorf("expected 23664 pieces got %d instead", info.NumPieces())
+	}
+
+	hash := info.PieceHash(1)
+	if hash != info.Pieces[1] {
+		t.Errorf("expected the second hash to be the second entry of pieces")
+	}
+
+	tests := []struct {
+		piece    int
+		expected int64
+	}{
+		{0, 262144},
+		{23662, 262144},
+		// 6203355136 - 23663 * 262144
+		{23663, 241664},
+		{23664, 0},
+		{-1, 0},
+	}
+
+	for _, test := range tests {
+		if size := info.PieceSize(test.piece); size != test.expected {
+			t.Errorf("expected piece %d to be %d bytes got %d instead", test.piece, test.expected, size)
+		}
+	}
+}
+
+func TestValidatePieces(t *testing.T) {
+	files := []FileEntry{{Length: 10, Path: []string{"a"}}, {Length: 25, Path: []string{"b"}}}
+
+	tests := []struct {
+		info        TorrentInfo
+		expectError bool
+	}{
+		{TorrentInfo{Files: files, PieceLength: 16, Pieces: make(PieceHashes, 3)}, false},
+		{TorrentInfo{Length: 32, PieceLength: 16, Pieces: make(PieceHashes, 2)}, false},
+		// one piece missing
+		{TorrentInfo{Files: files, PieceLength: 16, Pieces: make(PieceHashes, 2)}, true},
+		// one piece too many
+		{TorrentInfo{Length: 32, PieceLength: 16, Pieces: make(PieceHashes, 3)}, true},
+		{TorrentInfo{Files: files, PieceLength: 0, Pieces: make(PieceHashes, 3)}, true},
+		// a single piece that would have to be held in memory
+		{TorrentInfo{Length: 32, PieceLength: MaxPieceLength * 2, Pieces: make(PieceHashes, 1)}, true},
+		// negative lengths that still add up to the right number of pieces
+		{TorrentInfo{Files: []FileEntry{{Length: -10, Path: []string{"a"}}, {Length: 42, Path: []string{"b"}}}, PieceLength: 16, Pieces: make(PieceHashes, 2)}, true},
+	}
+
+	for _, test := range tests {
+		err := test.info.ValidatePieces()
+		if test.expectError && err == nil {
+			t.Errorf("expected an error for %+v", test.info)
+		}
+		if !test.expectError && err != nil {
+			t.Errorf("was not expecting an error for %+v got %s instead", test.info, err)
+		}
+	}
+
+	// torrents with broken pieces are rejected when parsed
+	data, err := bencode.Marshal(map[string]any{
+		"announce": "http://tracker",
+		"info":     TorrentInfo{Files: files, Name: "dir", PieceLength: 16, Pieces: make(PieceHashes, 2)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseTorrentFile(data); err == nil {
+		t.Errorf("expected an error when parsing a torrent with missing pieces")
+	}
+}
+
+func TestPieceSegments(t *testing.T) {
+	// files:  a [0, 10)  empty [10, 10)  b [10, 35)  c [35, 40)
+	// pieces: 0 [0, 16)  1 [16, 32)  2 [32, 40)
+	info := TorrentInfo{
+		Files: []FileEntry{
+			{Length: 10, Path: []string{"a"}},
+			{Length: 0, Path: []string{"empty"}},
+			{Length: 25, Path: []string{"b"}},
+			{Length: 5, Path: []string{"c"}},
+		},
+		PieceLength: 16,
+		Pieces:      make(PieceHashes, 3),
+	}
+
+	tests := []struct {
+		piece    int
+		expected []FileSegment
+	}{
+		{0, []FileSegment{{File: 0, Offset: 0, Length: 10}, {File: 2, Offset: 0, Length: 6}}},
+		{1, []FileSegment{{File: 2, Offset: 6, Length: 16}}},
+		{2, []FileSegment{{File: 2, Offset: 22, Length: 3}, {File: 3, Offset: 0, Length: 5}}},
+		{3, []FileSegment{}},
+	}
+
+	for _, test := range tests {
+		if res := info.PieceSegments(test.piece); !reflect.DeepEqual(res, test.expected) {
+			t.Errorf("expected piece %d to be made of %+v got %+v instead", test.piece, test.expected, res)
+		}
+	}
+
+	fileTests := []struct {
+		file       int
+		first, end int
+	}{
+		{0, 0, 1},
+		{1, 0, 0},
+		{2, 0, 3},
+		{3, 2, 3},
+		{4, 0, 0},
+	}
+
+	for _, test := range fileTests {
+		first, end := info.FilePieces(test.file)
+		if first != test.first || end != test.end {
+			t.Errorf("expected file %d to span pieces [%d, %d) got [%d, %d) instead", test.file, test.first, test.end, first, end)
+		}
+	}
+}
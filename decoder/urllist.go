@@ -0,0 +1,32 @@
+package decoder
+
+import (
+	"gotorrent/bencode"
+)
+
+// URLList is the "url-list" of a torrent, it's usually a list of urls
+// but a lot of torrents have a single url string instead.
+type URLList []string
+
+func (u URLList) MarshalBencode() ([]byte, error) {
+	return bencode.Marshal([]string(u))
+}
+
+func (u *URLList) UnmarshalBencode(data []byte) error {
+	var url string
+	if err := bencode.Unmarshal(data, &url); err == nil {
+		*u = URLList{}
+		if url != "" {
+			*u = URLList{url}
+		}
+		return nil
+	}
+
+	var urls []string
+	if err := bencode.Unmarshal(data, &urls); err != nil {
+		return err
+	}
+
+	*u = urls
+	return nil
+}
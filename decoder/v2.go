@@ -0,0 +1,181 @@
+package decoder
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"gotorrent/bencode"
+	"sort"
+)
+
+// FileTree is the BEP 52 "file tree" of a v2 torrent: a dict of directories
+// whose leaves are files stored under an empty key, e.g.
+//
+//	{"dir": {"a.txt": {"": {"length": 10, "pieces root": "..."}}}}
+type FileTree struct {
+	// File is set when the node is a file, Children when it's a directory
+	File     *FileTreeEntry
+	Children map[string]*FileTree
+}
+
+type FileTreeEntry struct {
+	Length int64 `bencode:"length"`
+	// PiecesRoot is the root of the file's merkle tree, it's missing for empty files
+	PiecesRoot string `bencode:"pieces root,omitempty"`
+	Attr       string `bencode:"attr,omitempty"`
+	// Extra keeps unknown keys
+	Extra map[string]bencode.RawMessage `bencode:",extra"`
+}
+
+func (t FileTree) MarshalBencode() ([]byte, error) {
+	dict := make(map[string]any)
+	for name, child := range t.Children {
+		dict[name] = child
+	}
+	if t.File != nil {
+		dict[""] = t.File
+	}
+
+	return bencode.Marshal(dict)
+}
+
+func (t *FileTree) UnmarshalBencode(data []byte) error {
+	var dict map[string]bencode.RawMessage
+	if err := bencode.Unmarshal(data, &dict); err != nil {
+		return err
+	}
+
+	*t = FileTree{}
+	for name, raw := range dict {
+		if name == "" {
+			t.File = &FileTreeEntry{}
+			if err := bencode.Unmarshal(raw, t.File); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if t.Children == nil {
+			t.Children = make(map[string]*FileTree)
+		}
+
+		child := &FileTree{}
+		if err := child.UnmarshalBencode(raw); err != nil {
+			return errors.New(fmt.Sprintf("Invalid file tree entry '%s': %s", name, err))
+		}
+		t.Children[name] = child
+	}
+
+	return nil
+}
+
+// FileEntryV2 is a file of a v2 torrent with its full path.
+type FileEntryV2 struct {
+	Path []string
+	FileTreeEntry
+}
+
+// HasV2 reports whether the torrent has v2 metadata ("meta version" 2 and a "file tree").
+func (i TorrentInfo) HasV2() bool {
+	return i.MetaVersion == 2 && i.FileTree != nil
+}
+
+// HasV1 reports whether the torrent has v1 metadata, hybrid torrents have both.
+func (i TorrentInfo) HasV1() bool {
+	return !i.HasV2() || len(i.Pieces) > 0
+}
+
+func (i TorrentInfo) IsHybrid() bool {
+	return i.HasV1() && i.HasV2()
+}
+
+// FilesV2 flattens the file tree, files come in the order their keys are sorted
+// which is also the order of the files in a hybrid torrent.
+func (i TorrentInfo) FilesV2() []FileEntryV2 {
+	files := make([]FileEntryV2, 0)
+	if i.FileTree != nil {
+		files = flattenFileTree(files, nil, i.FileTree)
+	}
+	return files
+}
+
+func flattenFileTree(files []FileEntryV2, path []string, t *FileTree) []FileEntryV2 {
+	if t.File != nil {
+		files = append(files, FileEntryV2{Path: path, FileTreeEntry: *t.File})
+	}
+
+	names := make([]string, 0, len(t.Children))
+	for name := range t.Children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		files = flattenFileTree(files, append(path[:len(path):len(path)], name), t.Children[name])
+	}
+	return files
+}
+
+// InfoHashV2 returns the SHA-256 of the bencoded info dict which identifies a v2 torrent.
+func (t TorrentFile) InfoHashV2() [32]byte {
+	if len(t.RawInfo) != 0 {
+		return sha256.Sum256(t.RawInfo)
+	}
+
+	info, _ := bencode.Marshal(t.Info)
+	return sha256.Sum256(info)
+}
+
+// TruncatedInfoHashV2 is the first 20 bytes of InfoHashV2, which is what v2 torrents
+// use in places that only have room for a v1 hash (handshakes, tracker announces...).
+func (t TorrentFile) TruncatedInfoHashV2() [20]byte {
+	var h [20]byte
+	full := t.InfoHashV2()
+	copy(h[:], full[:])
+	return h
+}
+
+// VerifyPieceLayers checks that every file larger than one piece has an entry in "piece layers"
+// and that hashing it gives back the file's pieces root.
+func (t TorrentFile) VerifyPieceLayers() error {
+	if !t.Info.HasV2() {
+		return errors.New("Torrent has no v2 metadata")
+	}
+
+	if err := checkPieceLengthV2(t.Info.PieceLength); err != nil {
+		return err
+	}
+
+	pieceLength := int64(t.Info.PieceLength)
+	for _, f := range t.Info.FilesV2() {
+		if f.Length == 0 {
+			continue
+		}
+
+		if len(f.PiecesRoot) != 32 {
+			return errors.New(fmt.Sprintf("Expected a 32 bytes pieces root for file %v got %d bytes instead", f.Path, len(f.PiecesRoot)))
+		}
+
+		if f.Length <= pieceLength {
+			continue
+		}
+
+		layer, ok := t.PieceLayers[f.PiecesRoot]
+		if !ok {
+			return errors.New(fmt.Sprintf("Missing piece layer for file %v", f.Path))
+		}
+
+		numPieces := (f.Length + pieceLength - 1) / pieceLength
+		if int64(len(layer)) != numPieces*32 {
+			return errors.New(fmt.Sprintf("Expected %d piece hashes for file %v got %d bytes instead", numPieces, f.Path, len(layer)))
+		}
+
+		root := rootFromPieceLayer([]byte(layer), t.Info.PieceLength)
+		if !bytes.Equal(root[:], []byte(f.PiecesRoot)) {
+			return errors.New(fmt.Sprintf("Piece layer of file %v does not match its pieces root", f.Path))
+		}
+	}
+
+	return nil
+}
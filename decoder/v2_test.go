@@ -0,0 +1,282 @@
+package decoder
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"gotorrent/bencode"
+	"gotorrent/internal/testutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testPieceLength = 2 * BlockSize
+
+// testData returns n bytes of deterministic content.
+// naiveRoot hashes every block and pads the leaves with zeros to a power of two,
+// which is how BEP 52 defines the pieces root.
+func naiveRoot(data []byte) [32]byte {
+	leaves := make([][32]byte, 0)
+	for start := 0; start < len(data); start += BlockSize {
+		leaves = append(leaves, sha256.Sum256(data[start:min(start+BlockSize, len(data))]))
+	}
+	for len(leaves)&(len(leaves)-1) != 0 {
+		leaves = append(leaves, [32]byte{})
+	}
+
+	for len(leaves) > 1 {
+		next := make([][32]byte, 0)
+		for i := 0; i < len(leaves); i += 2 {
+			next = append(next, sha256.Sum256(append(leaves[i][:], leaves[i+1][:]...)))
+		}
+		leaves = next
+	}
+	return leaves[0]
+}
+
+func TestHashFileV2(t *testing.T) {
+	tests := []struct {
+		size      int
+		numPieces int
+	}{
+		{1, 0},
+		{BlockSize, 0},
+		{BlockSize + 1, 0},
+		{testPieceLength, 0},
+		{testPieceLength + 1, 2},
+		// 5 pieces, the piece layer gets padded to 8
+		{4*testPieceLength + BlockSize/2, 5},
+	}
+
+	for _, test := range tests {
+		data := testutil.Content(test.size, 1)
+		res, err := HashFileV2(bytes.NewReader(data), testPieceLength)
+		if err != nil {
+			t.Fatalf("expected no error got %s instead", err)
+		}
+
+		if res.Length != int64(test.size) {
+			t.Errorf("expected length %d got %d instead", test.size, res.Length)
+		}
+		if res.PiecesRoot != naiveRoot(data) {
+			t.Errorf("unexpected pieces root for a %d bytes file", test.size)
+		}
+		if len(res.PieceLayer) != test.numPieces*32 {
+			t.Errorf("expected %d piece hashes for a %d bytes file got %d bytes instead", test.numPieces, test.size, len(res.PieceLayer))
+		}
+		if test.numPieces > 0 && rootFromPieceLayer(res.PieceLayer, testPieceLength) != res.PiecesRoot {
+			t.Errorf("expected the piece layer of a %d bytes file to hash to its root", test.size)
+		}
+	}
+
+	empty, err := HashFileV2(bytes.NewReader(nil), testPieceLength)
+	if err != nil || empty.Length != 0 || empty.PiecesRoot != [32]byte{} {
+		t.Errorf("unexpected result for an empty file %+v (%v)", empty, err)
+	}
+
+	if _, err := HashFileV2(bytes.NewReader(nil), 3*BlockSize); err == nil {
+		t.Errorf("expected an error when the piece length is not a power of two")
+	}
+	if _, err := HashFileV2(bytes.NewReader(nil), 2*MaxPieceLength); err == nil {
+		t.Errorf("expected an error when the piece length is too large")
+	}
+}
+
+// v2Torrent builds a v2 torrent with a big file, a small file and an empty one.
+func v2Torrent(t *testing.T) (*TorrentFile, []byte) {
+	big, err := HashFileV2(bytes.NewReader(testutil.Content(3*testPieceLength+10, 1)), testPieceLength)
+	if err != nil {
+		t.Fatal(err)
+	}
+	small, err := HashFileV2(bytes.NewReader(testutil.Content(100, 2)), testPieceLength)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	torrent := TorrentFile{
+		Announce: "http://tracker",
+		Info: TorrentInfo{
+			Name:        "dir",
+			PieceLength: testPieceLength,
+			MetaVersion: 2,
+			FileTree: &FileTree{Children: map[string]*FileTree{
+				"sub": {Children: map[string]*FileTree{
+					"big.bin": {File: &FileTreeEntry{Length: big.Length, PiecesRoot: string(big.PiecesRoot[:])}},
+				}},
+				"a.txt": {File: &FileTreeEntry{Length: small.Length, PiecesRoot: string(small.PiecesRoot[:])}},
+				"empty": {File: &FileTreeEntry{Length: 0}},
+			}},
+		},
+		PieceLayers: map[string]string{string(big.PiecesRoot[:]): string(big.PieceLayer)},
+	}
+
+	data, err := bencode.Marshal(torrent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := ParseTorrentFile(data)
+	if err != nil {
+		t.Fatalf("expected no error got %s instead", err)
+	}
+	return parsed, data
+}
+
+func TestParseV2(t *testing.T) {
+	parsed, data := v2Torrent(t)
+
+	if !parsed.Info.HasV2() || parsed.Info.HasV1() || parsed.Info.IsHybrid() {
+		t.Errorf("expected a v2 only torrent")
+	}
+
+	paths := make([][]string, 0)
+	for _, f := range parsed.Info.FilesV2() {
+		paths = append(paths, f.Path)
+	}
+	expected := [][]string{{"a.txt"}, {"empty"}, {"sub", "big.bin"}}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected files %v got %v instead", expected, paths)
+	}
+
+	if err := parsed.VerifyPieceLayers(); err != nil {
+		t.Errorf("expected piece layers to be valid got %s instead", err)
+	}
+
+	root, _ := bencode.Parse(data)
+	expectedHash := sha256.Sum256(root.Get("info").Raw())
+	if parsed.InfoHashV2() != expectedHash {
+		t.Errorf("expected the v2 info hash to be the SHA-256 of the info dict")
+	}
+	truncated := parsed.TruncatedInfoHashV2()
+	if !bytes.Equal(truncated[:], expectedHash[:20]) {
+		t.Errorf("expected the truncated hash to be the first 20 bytes of the v2 info hash")
+	}
+
+	// re-encoding the info dict gives the same hash
+	parsed.RawInfo = nil
+	if parsed.InfoHashV2() != expectedHash {
+		t.Errorf("expected the re-encoded info dict to have the same v2 hash")
+	}
+}
+
+func TestParseV2CorruptedLayer(t *testing.T) {
+	parsed, _ := v2Torrent(t)
+	for root, layer := range parsed.PieceLayers {
+		parsed.PieceLayers[root] = "x" + layer[1:]
+	}
+
+	// the info dict is untouched so only "piece layers" differs from a valid torrent
+	data, err := bencode.Marshal(parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseTorrentFile(data); err == nil {
+		t.Errorf("expected an error for a piece layer that doesn't match its pieces root")
+	}
+
+	// hybrid torrents too, their v1 pieces being fine doesn't matter
+	parsed.Info.Length = 10
+	parsed.Info.Pieces = make(PieceHashes, 1)
+	parsed.RawInfo = nil
+	hybrid, err := bencode.Marshal(parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseTorrentFile(hybrid); err == nil {
+		t.Errorf("expected an error for a hybrid torrent with a corrupted piece layer")
+	}
+}
+
+func TestParseV2UnnamedFile(t *testing.T) {
+	input := "d4:infod9:file treed0:d6:lengthi5e11:pieces root32:" + strings.Repeat("r", 32) +
+		"ee12:meta versioni2e4:name1:x12:piece lengthi16384eee"
+
+	if _, err := ParseTorrentFile([]byte(input)); err == nil {
+		t.Errorf("expected an error for a file at the root of the file tree")
+	}
+
+	// lint still gets to see it
+	parsed, err := ParseUnvalidated([]byte(input))
+	if err != nil || !HasErrors(Validate(parsed)) {
+		t.Errorf("expected the unvalidated torrent to have errors got %v instead", err)
+	}
+}
+
+func TestVerifyPieceLayersErrors(t *testing.T) {
+	tests := []func(torrent *TorrentFile){
+		// missing layer
+		func(torrent *TorrentFile) {
+			torrent.PieceLayers = nil
+		},
+		// corrupted layer
+		func(torrent *TorrentFile) {
+			for root, layer := range torrent.PieceLayers {
+				torrent.PieceLayers[root] = "x" + layer[1:]
+			}
+		},
+		// truncated layer
+		func(torrent *TorrentFile) {
+			for root, layer := range torrent.PieceLayers {
+				torrent.PieceLayers[root] = layer[32:]
+			}
+		},
+		// bad pieces root
+		func(torrent *TorrentFile) {
+			torrent.Info.FileTree.Children["a.txt"].File.PiecesRoot = "short"
+		},
+		// no v2 metadata
+		func(torrent *TorrentFile) {
+			torrent.Info.MetaVersion = 1
+		},
+	}
+
+	for i, test := range tests {
+		parsed, _ := v2Torrent(t)
+		test(parsed)
+
+		if err := parsed.VerifyPieceLayers(); err == nil {
+			t.Errorf("expected an error for case %d", i)
+		}
+	}
+}
+
+func TestFileTreeRoundTrip(t *testing.T) {
+	input := "d1:bd0:d4:attr1:x6:lengthi2eee3:dird1:ad0:d6:lengthi1eeeee"
+
+	var tree FileTree
+	if err := bencode.Unmarshal([]byte(input), &tree); err != nil {
+		t.Fatalf("expected no error got %s instead", err)
+	}
+
+	if tree.Children["dir"].Children["a"].File.Length != 1 || tree.Children["b"].File.Attr != "x" {
+		t.Errorf("unexpected file tree %+v", tree)
+	}
+
+	res, err := bencode.Marshal(tree)
+	if err != nil || string(res) != input {
+		t.Errorf("expected %s got %s (%v) instead", input, res, err)
+	}
+}
+
+func TestHybridHashes(t *testing.T) {
+	parsed, _ := v2Torrent(t)
+
+	// a hybrid torrent is v2 torrent that also has the v1 keys
+	parsed.Info.Length = 10
+	parsed.Info.Pieces = make(PieceHashes, 1)
+	parsed.RawInfo = nil
+
+	if !parsed.Info.IsHybrid() || !parsed.Info.HasV1() {
+		t.Fatalf("expected a hybrid torrent")
+	}
+
+	info, err := bencode.Marshal(parsed.Info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if parsed.InfoHash() != sha1.Sum(info) || parsed.InfoHashV2() != sha256.Sum256(info) {
+		t.Errorf("expected both hashes to be computed from the same info dict")
+	}
+}
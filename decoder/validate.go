@@ -0,0 +1,296 @@
+package decoder
+
+import (
+	"fmt"
+	"gotorrent/bencode"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+type Severity int
+
+const (
+	// Warning is for torrents that work but may not behave as expected (e.g. a tracker we can't reach)
+	Warning Severity = iota
+	// Error is for broken or unsafe torrents that should not be used
+	Error
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Warning:
+		return "warning"
+	case Error:
+		return "error"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// Problem is an issue found by Validate, Field is the path of the offending key
+// in the same form as bencode.Value.Lookup (e.g. "info.files.3.path.0").
+type Problem struct {
+	Severity Severity
+	Field    string
+	Message  string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s: %s", p.Severity, p.Field, p.Message)
+}
+
+// Validate checks a torrent for everything that would make it unusable or unsafe to download:
+// paths escaping the download directory, duplicate files, pieces that don't match the files,
+// invalid piece lengths, urls with unsupported schemes...
+// Use ParseUnvalidated to decode torrents meant to be validated since ParseTorrentFile
+// already rejects some of these.
+func Validate(t *TorrentFile) []Problem {
+	v := validator{problems: make([]Problem, 0)}
+
+	v.checkInfo(t)
+	v.checkTrackers(t)
+
+	for i, u := range t.URLList {
+		v.checkURL(fieldPath("url-list", i), u, "http", "https")
+	}
+	for i, u := range t.HTTPSeeds {
+		v.checkURL(fieldPath("httpseeds", i), u, "http", "https")
+	}
+
+	if t.Info.Private && len(t.Nodes) > 0 {
+		v.add(Warning, "nodes", "Private torrents don't use the DHT, the nodes are ignored")
+	}
+
+	return v.problems
+}
+
+// HasErrors reports whether any of the problems is an Error.
+func HasErrors(problems []Problem) bool {
+	for _, p := range problems {
+		if p.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
+type validator struct {
+	problems []Problem
+}
+
+func (v *validator) add(severity Severity, field string, format string, args ...any) {
+	v.problems = append(v.problems, Problem{Severity: severity, Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// fieldPath joins dict keys (strings) and list indexes (ints) into a Problem's Field.
+func fieldPath(parts ...any) string {
+	path := make([]string, len(parts))
+	for i, p := range parts {
+		switch p := p.(type) {
+		case string:
+			path[i] = p
+		case int:
+			path[i] = strconv.Itoa(p)
+		}
+	}
+	return bencode.JoinPath(path)
+}
+
+func (v *validator) checkInfo(t *TorrentFile) {
+	info := t.Info
+	start := len(v.problems)
+	v.checkName(fieldPath("info", "name"), info.Name)
+
+	if !info.HasV1() && !info.HasV2() {
+		v.add(Error, "info", "Torrent has neither v1 nor v2 metadata")
+	}
+
+	if info.PieceLength <= 0 {
+		v.add(Error, fieldPath("info", "piece length"), "Expected a positive piece length got %d instead", info.PieceLength)
+	} else if info.PieceLength > MaxPieceLength {
+		v.add(Error, fieldPath("info", "piece length"), "Piece length %d is larger than the max of %d", info.PieceLength, MaxPieceLength)
+	} else if info.HasV2() {
+		if err := checkPieceLengthV2(info.PieceLength); err != nil {
+			v.add(Error, fieldPath("info", "piece length"), "%s", err)
+		}
+	} else if info.PieceLength&(info.PieceLength-1) != 0 {
+		// BEP 3 doesn't require it but pretty much every client expects it
+		v.add(Warning, fieldPath("info", "piece length"), "Piece length %d is not a power of two", info.PieceLength)
+	}
+
+	if info.HasV1() {
+		v.checkFilesV1(info)
+		if info.PieceLength > 0 && info.PieceLength <= MaxPieceLength {
+			if err := info.ValidatePieces(); err != nil {
+				v.add(Error, fieldPath("info", "pieces"), "%s", err)
+			}
+		}
+	}
+
+	if info.HasV2() {
+		v.checkFilesV2(info)
+
+		// the layers can only be checked against files & a piece length that are valid,
+		// anything wrong with those was already reported
+		if !HasErrors(v.problems[start:]) {
+			if err := t.VerifyPieceLayers(); err != nil {
+				v.add(Error, "piece layers", "%s", err)
+			}
+		}
+	}
+}
+
+// checkName checks a single path component, the unsafe ones are errors while
+// the ones that only can't be created on some systems are warnings.
+func (v *validator) checkName(field string, name string) bool {
+	if err := CheckPathComponent(name); err != nil {
+		v.add(Error, field, "%s", err)
+		return false
+	}
+
+	if IsReservedName(name) {
+		v.add(Warning, field, "'%s' is a reserved file name on windows", name)
+	}
+	return true
+}
+
+func (v *validator) checkFilesV1(info TorrentInfo) {
+	if info.Length < 0 {
+		v.add(Error, fieldPath("info", "length"), "Expected a positive length got %d instead", info.Length)
+	}
+	if info.Length > 0 && info.IsMultiFile() {
+		v.add(Error, "info", "Torrent has both a length and a files list")
+	}
+
+	paths := make([][]string, 0, len(info.Files))
+	for i, f := range info.Files {
+		if f.Length < 0 {
+			v.add(Error, fieldPath("info", "files", i, "length"), "Expected a positive length got %d instead", f.Length)
+		}
+
+		if len(f.Path) == 0 {
+			v.add(Error, fieldPath("info", "files", i, "path"), "File has an empty path")
+			continue
+		}
+
+		ok := true
+		for j, part := range f.Path {
+			ok = v.checkName(fieldPath("info", "files", i, "path", j), part) && ok
+		}
+		if f.IsSymlink() {
+			v.checkSymlink(fieldPath("info", "files", i, "symlink path"), f.SymlinkPath)
+		}
+
+		// padding files are all named after their size so they're expected to repeat
+		if ok && !f.IsPadding() {
+			paths = append(paths, f.Path)
+		}
+	}
+
+	v.checkDuplicates(fieldPath("info", "files"), paths)
+}
+
+func (v *validator) checkFilesV2(info TorrentInfo) {
+	files := info.FilesV2()
+	if len(files) == 0 {
+		v.add(Error, fieldPath("info", "file tree"), "File tree has no files")
+	}
+
+	for _, f := range files {
+		field := bencode.JoinPath(append([]string{"info", "file tree"}, f.Path...))
+		if len(f.Path) == 0 {
+			v.add(Error, fieldPath("info", "file tree"), "File tree has a file without a name")
+			continue
+		}
+
+		for _, part := range f.Path {
+			v.checkName(field, part)
+		}
+
+		if f.Length < 0 {
+			v.add(Error, field, "Expected a positive length got %d instead", f.Length)
+		}
+		if f.Length > 0 && len(f.PiecesRoot) != 32 {
+			v.add(Error, field, "Expected a 32 bytes pieces root got %d bytes instead", len(f.PiecesRoot))
+		}
+	}
+}
+
+// checkSymlink makes sure a symlink target stays within the torrent, it's relative to the torrent's root.
+func (v *validator) checkSymlink(field string, target []string) {
+	if len(target) == 0 {
+		v.add(Error, field, "Symlink has no target")
+		return
+	}
+
+	for i, part := range target {
+		v.checkName(field+"."+strconv.Itoa(i), part)
+	}
+}
+
+// checkDuplicates reports files that are listed twice, files whose path only differ by case
+// (they'd overwrite each other on case insensitive file systems) and files that are also used as a directory.
+func (v *validator) checkDuplicates(field string, paths [][]string) {
+	seen := make(map[string]bool)
+	folded := make(map[string]string)
+	for _, path := range paths {
+		key := strings.Join(path, "/")
+		if seen[key] {
+			v.add(Error, field, "File '%s' is listed more than once", key)
+			continue
+		}
+		seen[key] = true
+
+		if other, ok := folded[strings.ToLower(key)]; ok {
+			v.add(Warning, field, "Files '%s' and '%s' only differ by case", other, key)
+		}
+		folded[strings.ToLower(key)] = key
+	}
+
+	for _, path := range paths {
+		for i := 1; i < len(path); i++ {
+			dir := strings.Join(path[:i], "/")
+			if seen[dir] {
+				v.add(Error, field, "'%s' is both a file and the directory of '%s'", dir, strings.Join(path, "/"))
+			}
+		}
+	}
+}
+
+func (v *validator) checkTrackers(t *TorrentFile) {
+	if t.Announce != "" {
+		v.checkURL("announce", t.Announce, "http", "https", "udp")
+	}
+
+	for i, tier := range t.AnnounceList {
+		for j, tracker := range tier {
+			v.checkURL(fieldPath("announce-list", i, j), tracker, "http", "https", "udp")
+		}
+	}
+
+	if t.Announce == "" && len(t.AnnounceList) == 0 && len(t.Nodes) == 0 && len(t.URLList) == 0 && len(t.HTTPSeeds) == 0 {
+		v.add(Warning, "announce", "Torrent has no trackers, DHT nodes or web seeds, peers can only be found through the DHT")
+	}
+}
+
+// checkURL reports malformed urls as errors and urls we can't use as warnings
+// since other clients may support them (e.g. wss:// trackers).
+func (v *validator) checkURL(field string, rawURL string, schemes ...string) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		v.add(Error, field, "Invalid url '%s': %s", rawURL, err)
+		return
+	}
+
+	if u.Host == "" {
+		v.add(Error, field, "Url '%s' has no host", rawURL)
+		return
+	}
+
+	for _, scheme := range schemes {
+		if strings.EqualFold(u.Scheme, scheme) {
+			return
+		}
+	}
+	v.add(Warning, field, "Url '%s' has an unsupported scheme, expected one of %s", rawURL, strings.Join(schemes, ", "))
+}
@@ -0,0 +1,152 @@
+package decoder
+
+import (
+	"strings"
+	"testing"
+)
+
+// validTorrent returns a multi-file torrent Validate has nothing to say about.
+func validTorrent() *TorrentFile {
+	return &TorrentFile{
+		Announce:     "http://tracker/announce",
+		AnnounceList: [][]string{{"http://tracker/announce", "udp://tracker:80"}},
+		URLList:      URLList{"https://seed/"},
+		Info: TorrentInfo{
+			Files: []FileEntry{
+				{Length: 10, Path: []string{"dir", "a.txt"}},
+				{Length: 16374, Path: []string{".pad", "16374"}, Attr: "p"},
+				{Length: 20, Path: []string{"b.txt"}},
+				{Length: 16364, Path: []string{".pad", "16364"}, Attr: "p"},
+				{Length: 5, Path: []string{"c.txt"}},
+			},
+			Name:        "torrent",
+			PieceLength: 16384,
+			Pieces:      make(PieceHashes, 3),
+		},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if problems := Validate(validTorrent()); len(problems) != 0 {
+		t.Fatalf("expected no problems got %v instead", problems)
+	}
+
+	tests := []struct {
+		name     string
+		modify   func(t *TorrentFile)
+		severity Severity
+		field    string
+	}{
+		{"parent dir", func(t *TorrentFile) { t.Info.Files[0].Path = []string{"..", "..", "etc", "passwd"} }, Error, "info.files.0.path.0"},
+		{"absolute path", func(t *TorrentFile) { t.Info.Files[2].Path = []string{"/etc/passwd"} }, Error, "info.files.2.path.0"},
+		{"separator", func(t *TorrentFile) { t.Info.Files[2].Path = []string{`a\..\b`} }, Error, "info.files.2.path.0"},
+		{"empty path", func(t *TorrentFile) { t.Info.Files[2].Path = []string{} }, Error, "info.files.2.path"},
+		{"bad name", func(t *TorrentFile) { t.Info.Name = ".." }, Error, "info.name"},
+		{"reserved name", func(t *TorrentFile) { t.Info.Files[4].Path = []string{"con.txt"} }, Warning, "info.files.4.path.0"},
+		{"trailing dot", func(t *TorrentFile) { t.Info.Name = "name." }, Warning, "info.name"},
+		{"duplicate", func(t *TorrentFile) { t.Info.Files[4].Path = []string{"b.txt"} }, Error, "info.files"},
+		{"case", func(t *TorrentFile) { t.Info.Files[4].Path = []string{"B.TXT"} }, Warning, "info.files"},
+		{"file and dir", func(t *TorrentFile) { t.Info.Files[4].Path = []string{"dir"} }, Error, "info.files"},
+		{"symlink", func(t *TorrentFile) {
+			t.Info.Files[4].Attr = "l"
+			t.Info.Files[4].SymlinkPath = []string{"..", "outside"}
+		}, Error, "info.files.4.symlink path.0"},
+		{"negative length", func(t *TorrentFile) { t.Info.Files[4].Length = -5 }, Error, "info.files.4.length"},
+		{"length and files", func(t *TorrentFile) { t.Info.Length = 1 }, Error, "info"},
+		{"pieces count", func(t *TorrentFile) { t.Info.Pieces = make(PieceHashes, 4) }, Error, "info.pieces"},
+		{"missing pieces", func(t *TorrentFile) { t.Info.Pieces = make(PieceHashes, 2) }, Error, "info.pieces"},
+		{"zero piece length", func(t *TorrentFile) { t.Info.PieceLength = 0 }, Error, "info.piece length"},
+		{"not a power of two", func(t *TorrentFile) {
+			t.Info.PieceLength = 16383
+			t.Info.Files[1].Length = 16373
+			t.Info.Files[3].Length = 16363
+		}, Warning, "info.piece length"},
+		// a power of two but way too large to be hashed
+		{"huge piece length", func(t *TorrentFile) { t.Info.PieceLength = 1 << 46 }, Error, "info.piece length"},
+		{"announce scheme", func(t *TorrentFile) { t.Announce = "wss://tracker" }, Warning, "announce"},
+		{"file url", func(t *TorrentFile) { t.AnnounceList[0][1] = "file:///etc/passwd" }, Error, "announce-list.0.1"},
+		{"bad url", func(t *TorrentFile) { t.Announce = "http://[::1" }, Error, "announce"},
+		{"web seed", func(t *TorrentFile) { t.URLList = URLList{"ftp://seed/"} }, Warning, "url-list.0"},
+		{"http seed", func(t *TorrentFile) { t.HTTPSeeds = URLList{"udp://seed"} }, Warning, "httpseeds.0"},
+		{"no trackers", func(t *TorrentFile) {
+			t.Announce = ""
+			t.AnnounceList = nil
+			t.URLList = nil
+		}, Warning, "announce"},
+		{"private with nodes", func(t *TorrentFile) {
+			t.Info.Private = true
+			t.Nodes = []Node{{"router", 6881}}
+		}, Warning, "nodes"},
+	}
+
+	for _, test := range tests {
+		torrent := validTorrent()
+		test.modify(torrent)
+
+		problems := Validate(torrent)
+		found := false
+		for _, p := range problems {
+			if p.Field == test.field && p.Severity == test.severity {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("%s: expected a %s on %s got %v instead", test.name, test.severity, test.field, problems)
+		}
+		if HasErrors(problems) != (test.severity == Error) {
+			t.Errorf("%s: expected HasErrors to be %v for %v", test.name, test.severity == Error, problems)
+		}
+	}
+}
+
+func TestValidateV2(t *testing.T) {
+	torrent, _ := v2Torrent(t)
+	if problems := Validate(torrent); len(problems) != 0 {
+		t.Fatalf("expected no problems got %v instead", problems)
+	}
+
+	torrent.Info.FileTree.Children["a.b"] = &FileTree{Children: map[string]*FileTree{
+		"..": {File: &FileTreeEntry{Length: 100, PiecesRoot: "short"}},
+	}}
+	problems := Validate(torrent)
+	expected := []Problem{
+		{Error, `info.file tree.a\.b.\.\.`, "Invalid path component '..'"},
+		{Error, `info.file tree.a\.b.\.\.`, "Expected a 32 bytes pieces root got 5 bytes instead"},
+	}
+	if len(problems) != len(expected) || problems[0] != expected[0] || problems[1] != expected[1] {
+		t.Errorf("expected %v got %v instead", expected, problems)
+	}
+
+	torrent.Info.PieceLength = 3 * BlockSize
+	if !HasErrors(Validate(torrent)) {
+		t.Errorf("expected an error for a v2 piece length that's not a power of two")
+	}
+
+	// piece layers have to match the pieces roots
+	torrent, _ = v2Torrent(t)
+	for root, layer := range torrent.PieceLayers {
+		torrent.PieceLayers[root] = "x" + layer[1:]
+	}
+	problems = Validate(torrent)
+	if len(problems) != 1 || problems[0].Severity != Error || problems[0].Field != "piece layers" {
+		t.Errorf("expected a piece layers error got %v instead", problems)
+	}
+}
+
+func TestParseUnvalidated(t *testing.T) {
+	data := multiFileTorrent(t, 16384, FileEntry{Length: 100000, Path: []string{"a"}})
+	broken := strings.Replace(string(data), "6:pieces140:", "6:pieces120:", 1)
+	broken = strings.Replace(broken, strings.Repeat("\x00", 140), strings.Repeat("\x00", 120), 1)
+
+	if _, err := ParseTorrentFile([]byte(broken)); err == nil {
+		t.Fatalf("expected ParseTorrentFile to reject the pieces")
+	}
+
+	torrent, err := ParseUnvalidated([]byte(broken))
+	if err != nil {
+		t.Fatalf("expected no error got %s instead", err)
+	}
+	if problems := Validate(torrent); !HasErrors(problems) || problems[0].Field != "info.pieces" {
+		t.Errorf("expected a pieces error got %v instead", problems)
+	}
+}
@@ -0,0 +1,199 @@
+package editor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"gotorrent/bencode"
+	"gotorrent/decoder"
+	"slices"
+)
+
+// Edit describes the changes to make to a torrent, only what's outside of the info dict
+// can be edited since anything in it (name, files, "source", "private"...) changes the info hash.
+// The tracker changes are applied in the order of the fields.
+type Edit struct {
+	// SetTrackers replaces every tier when it's not nil, an empty list removes all the trackers
+	SetTrackers [][]string
+	// Reorder is the new order of the tiers given as their current indexes, e.g. {2, 0, 1}
+	Reorder []int
+	// RemoveTrackers removes these urls from every tier, tiers left empty are dropped
+	RemoveTrackers []string
+	// AddTiers appends tiers after the existing ones
+	AddTiers [][]string
+
+	// Comment and CreatedBy are only changed when they're not nil
+	Comment   *string
+	CreatedBy *string
+
+	AddWebSeeds    []string
+	RemoveWebSeeds []string
+
+	StripCreationDate bool
+}
+
+func (e Edit) editsTrackers() bool {
+	return e.SetTrackers != nil || e.Reorder != nil || len(e.RemoveTrackers) > 0 || len(e.AddTiers) > 0
+}
+
+// Apply makes the changes described by e to t, t is left untouched when an error is returned.
+func Apply(t *decoder.TorrentFile, e Edit) error {
+	if e.editsTrackers() {
+		tiers, err := editTiers(Tiers(t), e)
+		if err != nil {
+			return err
+		}
+		setTiers(t, tiers)
+	}
+
+	if e.Comment != nil {
+		t.Comment = *e.Comment
+	}
+	if e.CreatedBy != nil {
+		t.CreatedBy = *e.CreatedBy
+	}
+
+	for _, ws := range e.RemoveWebSeeds {
+		t.URLList = slices.DeleteFunc(t.URLList, func(u string) bool { return u == ws })
+	}
+	for _, ws := range e.AddWebSeeds {
+		if !slices.Contains(t.URLList, ws) {
+			t.URLList = append(t.URLList, ws)
+		}
+	}
+
+	if e.StripCreationDate {
+		t.CreationDate = 0
+	}
+
+	return nil
+}
+
+// Tiers returns the trackers of t, the "announce-list" when there is one
+// and otherwise a single tier holding "announce" as described in BEP 12.
+func Tiers(t *decoder.TorrentFile) [][]string {
+	if len(t.AnnounceList) > 0 {
+		tiers := make([][]string, len(t.AnnounceList))
+		for i, tier := range t.AnnounceList {
+			tiers[i] = slices.Clone(tier)
+		}
+		return tiers
+	}
+
+	if t.Announce != "" {
+		return [][]string{{t.Announce}}
+	}
+	return [][]string{}
+}
+
+func editTiers(tiers [][]string, e Edit) ([][]string, error) {
+	if e.SetTrackers != nil {
+		tiers = slices.Clone(e.SetTrackers)
+	}
+
+	if e.Reorder != nil {
+		if len(e.Reorder) != len(tiers) {
+			return nil, errors.New(fmt.Sprintf("Expected the new order to list all the %d tiers got %d instead", len(tiers), len(e.Reorder)))
+		}
+
+		reordered := make([][]string, len(tiers))
+		seen := make(map[int]bool)
+		for i, idx := range e.Reorder {
+			if idx < 0 || idx >= len(tiers) || seen[idx] {
+				return nil, errors.New(fmt.Sprintf("Invalid tier order %v, each tier from 0 to %d must appear once", e.Reorder, len(tiers)-1))
+			}
+			seen[idx] = true
+			reordered[i] = tiers[idx]
+		}
+		tiers = reordered
+	}
+
+	if len(e.RemoveTrackers) > 0 {
+		kept := make([][]string, 0, len(tiers))
+		for _, tier := range tiers {
+			tier = slices.DeleteFunc(slices.Clone(tier), func(u string) bool { return slices.Contains(e.RemoveTrackers, u) })
+			if len(tier) > 0 {
+				kept = append(kept, tier)
+			}
+		}
+		tiers = kept
+	}
+
+	for _, tier := range e.AddTiers {
+		if len(tier) > 0 {
+			tiers = append(tiers, tier)
+		}
+	}
+
+	return tiers, nil
+}
+
+// setTiers writes the trackers back the way creator does: the first tracker is "announce"
+// and "announce-list" is only used when there is more than one tracker.
+func setTiers(t *decoder.TorrentFile, tiers [][]string) {
+	t.Announce = ""
+	t.AnnounceList = nil
+
+	if len(tiers) > 0 && len(tiers[0]) > 0 {
+		t.Announce = tiers[0][0]
+	}
+	if len(tiers) > 1 || (len(tiers) == 1 && len(tiers[0]) > 1) {
+		t.AnnounceList = tiers
+	}
+}
+
+// Encode writes the changes made to t back into data, the file t was decoded from.
+// Only the keys whose value changed are re-encoded, everything else (the info dict,
+// unknown keys, their order...) keeps the exact bytes it had so the info hash doesn't change.
+// A nil data encodes t from scratch.
+func Encode(data []byte, t *decoder.TorrentFile) ([]byte, error) {
+	if data == nil {
+		return bencode.Marshal(t)
+	}
+
+	original, err := decoder.ParseUnvalidated(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// compare the encoding of each key before & after the edit to find what changed
+	before, err := encodedKeys(original)
+	if err != nil {
+		return nil, err
+	}
+	after, err := encodedKeys(t)
+	if err != nil {
+		return nil, err
+	}
+
+	var dict bencode.OrderedDict
+	if err := bencode.Unmarshal(data, &dict); err != nil {
+		return nil, err
+	}
+
+	for key, val := range after {
+		if !bytes.Equal(val, before[key]) {
+			dict.SetSorted(key, val)
+		}
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			dict.Delete(key)
+		}
+	}
+
+	return bencode.Marshal(dict)
+}
+
+func encodedKeys(t *decoder.TorrentFile) (map[string]bencode.RawMessage, error) {
+	b, err := bencode.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys map[string]bencode.RawMessage
+	if err := bencode.Unmarshal(b, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
@@ -0,0 +1,162 @@
+package editor
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"gotorrent/bencode"
+	"gotorrent/decoder"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func testTorrent(t *testing.T) *decoder.TorrentFile {
+	data, err := os.ReadFile("../decoder/files/test.torrent")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	torrent, err := decoder.ParseTorrentFile(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return torrent
+}
+
+func TestApplyTrackers(t *testing.T) {
+	ubuntu := "https://torrent.ubuntu.com/announce"
+	ipv6 := "https://ipv6.torrent.ubuntu.com/announce"
+
+	tests := []struct {
+		edit             Edit
+		expectedAnnounce string
+		expectedList     [][]string
+	}{
+		{Edit{Reorder: []int{1, 0}}, ipv6, [][]string{{ipv6}, {ubuntu}}},
+		{Edit{RemoveTrackers: []string{ubuntu}}, ipv6, nil},
+		{Edit{AddTiers: [][]string{{"udp://a:80", "udp://b:80"}}}, ubuntu, [][]string{{ubuntu}, {ipv6}, {"udp://a:80", "udp://b:80"}}},
+		{Edit{SetTrackers: [][]string{{"http://new"}}}, "http://new", nil},
+		{Edit{SetTrackers: [][]string{}}, "", nil},
+		// everything at once: set, reorder, remove then add
+		{
+			Edit{
+				SetTrackers:    [][]string{{"http://a", "http://b"}, {"http://c"}},
+				Reorder:        []int{1, 0},
+				RemoveTrackers: []string{"http://a"},
+				AddTiers:       [][]string{{"http://d"}, {}},
+			},
+			"http://c", [][]string{{"http://c"}, {"http://b"}, {"http://d"}},
+		},
+	}
+
+	for _, test := range tests {
+		torrent := testTorrent(t)
+		if err := Apply(torrent, test.edit); err != nil {
+			t.Fatalf("expected no error for %+v got %s instead", test.edit, err)
+		}
+
+		if torrent.Announce != test.expectedAnnounce || !reflect.DeepEqual(torrent.AnnounceList, test.expectedList) {
+			t.Errorf("expected %s & %v for %+v got %s & %v instead", test.expectedAnnounce, test.expectedList, test.edit, torrent.Announce, torrent.AnnounceList)
+		}
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	tests := []Edit{
+		{Reorder: []int{0}},
+		{Reorder: []int{0, 0}},
+		{Reorder: []int{0, 2}},
+		{Reorder: []int{-1, 0}},
+	}
+
+	for _, test := range tests {
+		torrent := testTorrent(t)
+		if err := Apply(torrent, test); err == nil {
+			t.Errorf("expected an error for %+v", test)
+		}
+		if !reflect.DeepEqual(torrent, testTorrent(t)) {
+			t.Errorf("expected the torrent to be left untouched for %+v", test)
+		}
+	}
+}
+
+func TestApplyMetadata(t *testing.T) {
+	torrent := testTorrent(t)
+	torrent.URLList = []string{"http://old", "http://kept"}
+
+	comment, createdBy := "new comment", ""
+	err := Apply(torrent, Edit{
+		Comment:           &comment,
+		CreatedBy:         &createdBy,
+		AddWebSeeds:       []string{"http://kept", "http://new"},
+		RemoveWebSeeds:    []string{"http://old"},
+		StripCreationDate: true,
+	})
+	if err != nil {
+		t.Fatalf("expected no error got %s instead", err)
+	}
+
+	if torrent.Comment != comment || torrent.CreatedBy != "" || torrent.CreationDate != 0 ||
+		!reflect.DeepEqual([]string(torrent.URLList), []string{"http://kept", "http://new"}) {
+		t.Errorf("unexpected torrent %+v", torrent)
+	}
+
+	// nothing else changes
+	torrent = testTorrent(t)
+	if err := Apply(torrent, Edit{}); err != nil || !reflect.DeepEqual(torrent, testTorrent(t)) {
+		t.Errorf("expected an empty edit to change nothing")
+	}
+}
+
+func TestEncodeKeepsInfo(t *testing.T) {
+	// "info" is not canonical (unsorted keys) so re-encoding it would change the hash
+	info := "d6:lengthi5e4:name1:a12:piece lengthi16384e6:pieces20:xxxxxxxxxxxxxxxxxxxx3:zzzi1e7:privatei1ee"
+	// neither is the rest: unsorted unknown keys, a non canonical int and a single url-list string
+	input := "d8:announce8:http://a7:comment3:old2:zzi01e4:info" + info + "1:bi2e8:url-list5:http:e"
+
+	torrent, err := decoder.ParseTorrentFile([]byte(input))
+	if err != nil {
+		t.Fatalf("expected no error got %s instead", err)
+	}
+
+	comment := "new"
+	if err := Apply(torrent, Edit{Comment: &comment, AddTiers: [][]string{{"http://b"}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	encoded, err := Encode([]byte(input), torrent)
+	if err != nil {
+		t.Fatalf("expected no error got %s instead", err)
+	}
+
+	// only the edited keys change, new ones are inserted where they sort
+	expected := "d8:announce8:http://a13:announce-listll8:http://ael8:http://bee7:comment3:new2:zzi01e4:info" + info +
+		"1:bi2e8:url-list5:http:e"
+	if string(encoded) != expected {
+		t.Errorf("expected %s got %s instead", expected, encoded)
+	}
+
+	edited, err := decoder.ParseTorrentFile(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edited.InfoHash() != sha1.Sum([]byte(info)) {
+		t.Errorf("expected the info hash to be unchanged")
+	}
+
+	// removed keys are dropped
+	empty := ""
+	if err := Apply(edited, Edit{Comment: &empty}); err != nil {
+		t.Fatal(err)
+	}
+	encoded, _ = Encode(encoded, edited)
+	if bytes.Contains(encoded, []byte("7:comment")) {
+		t.Errorf("expected the comment to be removed got %s instead", encoded)
+	}
+
+	// without the original file it's a plain Marshal
+	plain, _ := bencode.Marshal(torrent)
+	if encoded, _ := Encode(nil, torrent); !bytes.Equal(encoded, plain) {
+		t.Errorf("expected %s got %s instead", plain, encoded)
+	}
+}
@@ -6,10 +6,6 @@ import (
 )
 
 func TestEncodeDict(t *testing.T) {
-	// maps in go are not ordered, meaning iterating through the keys and encoding them
-	// will not produce the same result upon different re-runs
-	// thus the test here a quite simple
-	// @TODO: maybe think of a way to test with an unordered dict?
 	tests := []struct {
 		input    decoder.BencodeDict
 		expected string
@@ -34,16 +30,103 @@ func TestEncodeDict(t *testing.T) {
 			input:    decoder.BencodeDict{"h": decoder.BencodeDict{"h": "h"}},
 			expected: "d1:hd1:h1:hee",
 		},
+		// keys should always be sorted as raw byte strings
+		{
+			input:    decoder.BencodeDict{"b": 1, "a": 2, "B": 3, "ab": 4},
+			expected: "d1:Bi3e1:ai2e2:abi4e1:bi1ee",
+		},
+		{
+			input:    decoder.BencodeDict{"z": decoder.BencodeDict{"y": 1, "x": 2}, "a": "a"},
+			expected: "d1:a1:a1:zd1:xi2e1:yi1eee",
+		},
 	}
 
 	for _, test := range tests {
-		result := encodeDict(test.input)
+		// run a few times since go randomizes map iteration order
+		for range 10 {
+			result, err := Encode(test.input)
+			if err != nil {
+				t.Fatalf("expected no error got %s instead", err)
+			}
+
+			if result != test.expected {
+				t.Fatalf("input = %+v expected %s = , got = %s", test.input, test.expected, result)
+			}
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		input    any
+		expected string
+	}{
+		{
+			input:    int64(6203355136),
+			expected: "i6203355136e",
+		},
+		{
+			input:    uint64(18446744073709551615),
+			expected: "i18446744073709551615e",
+		},
+		{
+			input:    []byte("hi"),
+			expected: "2:hi",
+		},
+		{
+			input:    []string{"a", "b"},
+			expected: "l1:a1:be",
+		},
+		{
+			input:    [][]string{{"a"}, {"b", "c"}},
+			expected: "ll1:ael1:b1:cee",
+		},
+		{
+			input:    struct{ B, A int }{B: 1, A: 2},
+			expected: "d1:Ai2e1:Bi1ee",
+		},
+		{
+			input:    []int{1, 2},
+			expected: "li1ei2ee",
+		},
+		{
+			input:    map[string][]uint16{"b": {1}, "a": {}},
+			expected: "d1:ale1:bli1eee",
+		},
+		{
+			input:    &struct{ P *int }{},
+			expected: "de",
+		},
+	}
+
+	for _, test := range tests {
+		result, err := Encode(test.input)
+		if err != nil {
+			t.Fatalf("expected no error got %s instead", err)
+		}
+
 		if result != test.expected {
 			t.Errorf("input = %+v expected %s = , got = %s", test.input, test.expected, result)
 		}
 	}
 }
 
+func TestEncodeUnsupported(t *testing.T) {
+	tests := []any{
+		nil,
+		1.5,
+		[]float64{1},
+		decoder.BencodeDict{"h": 1.5},
+		[]any{"h", map[int]int{}},
+	}
+
+	for _, test := range tests {
+		if _, err := Encode(test); err == nil {
+			t.Errorf("expected an error for input %+v", test)
+		}
+	}
+}
+
 func TestEncodeList(t *testing.T) {
 	tests := []struct {
 		input    []any
@@ -68,7 +151,11 @@ func TestEncodeList(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		result := encodeList(test.input)
+		result, err := Encode(test.input)
+		if err != nil {
+			t.Fatalf("expected no error got %s instead", err)
+		}
+
 		if result != test.expected {
 			t.Errorf("input = %+v expected %s = , got = %s", test.input, test.expected, result)
 		}
@@ -91,7 +178,11 @@ func TestEncodeString(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		result := encodeString(test.input)
+		result, err := Encode(test.input)
+		if err != nil {
+			t.Fatalf("expected no error got %s instead", err)
+		}
+
 		if result != test.expected {
 			t.Errorf("input = %s expected %s = , got = %s", test.input, test.expected, result)
 		}
@@ -100,7 +191,7 @@ func TestEncodeString(t *testing.T) {
 
 func TestEncodeInt(t *testing.T) {
 	tests := []struct {
-		input    int
+		input    int64
 		expected string
 	}{
 		{
@@ -118,7 +209,11 @@ func TestEncodeInt(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		result := encodeInt(test.input)
+		result, err := Encode(test.input)
+		if err != nil {
+			t.Fatalf("expected no error got %s instead", err)
+		}
+
 		if result != test.expected {
 			t.Errorf("input = %d expected %s = , got = %s", test.input, test.expected, result)
 		}
@@ -0,0 +1,34 @@
+// Package testutil has the helpers shared by the tests of the packages working on torrent data.
+package testutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Content returns n bytes of data that doesn't repeat too often, different seeds
+// give different data.
+func Content(n int, seed byte) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(i*7) + seed
+	}
+	return data
+}
+
+// WriteFiles creates the files (paths are slash separated) under a temp dir and returns the temp dir.
+func WriteFiles(t *testing.T, files map[string][]byte) string {
+	t.Helper()
+	root := t.TempDir()
+	for path, content := range files {
+		full := filepath.Join(root, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
@@ -0,0 +1,267 @@
+package magnet
+
+import (
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gotorrent/decoder"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Magnet is a magnet link (BEP 9), a torrent is identified by its v1 info hash (btih),
+// its v2 one (btmh, BEP 52) or both for hybrid torrents.
+type Magnet struct {
+	InfoHash    [20]byte
+	InfoHashV2  [32]byte
+	DisplayName string   // dn
+	Trackers    []string // tr
+	WebSeeds    []string // ws
+	Peers       []string // x.pe, as host:port
+	// SelectOnly holds the indexes of the files to download (so, BEP 53), nil means all of them
+	SelectOnly []FileRange
+}
+
+// FileRange is a range of file indexes, both ends included.
+type FileRange struct {
+	First, Last int
+}
+
+const (
+	btihPrefix = "urn:btih:"
+	btmhPrefix = "urn:btmh:"
+	// multihash header of a 32 bytes SHA-256 digest
+	sha256Multihash = "1220"
+)
+
+func (m Magnet) HasV1() bool {
+	return m.InfoHash != [20]byte{}
+}
+
+func (m Magnet) HasV2() bool {
+	return m.InfoHashV2 != [32]byte{}
+}
+
+// Selects reports whether the file at index idx is part of SelectOnly.
+func (m Magnet) Selects(idx int) bool {
+	if m.SelectOnly == nil {
+		return true
+	}
+
+	for _, r := range m.SelectOnly {
+		if idx >= r.First && idx <= r.Last {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse decodes a magnet link, it must have at least one btih or btmh "xt".
+// Repeated parameters can also be numbered (e.g. "tr.1", "tr.2") as some clients do.
+func Parse(uri string) (*Magnet, error) {
+	if len(uri) < len("magnet:?") || !strings.EqualFold(uri[:len("magnet:?")], "magnet:?") {
+		return nil, errors.New("Expected a link starting with 'magnet:?'")
+	}
+
+	// the query is walked by hand instead of using url.ParseQuery since the order of
+	// the parameters matters, the first trackers are the ones to try first
+	m := &Magnet{}
+	for _, param := range strings.Split(uri[len("magnet:?"):], "&") {
+		if param == "" {
+			continue
+		}
+
+		rawKey, rawValue, _ := strings.Cut(param, "=")
+		key, err := url.QueryUnescape(rawKey)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid magnet link: %s", err))
+		}
+		value, err := url.QueryUnescape(rawValue)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid magnet link: %s", err))
+		}
+
+		// "x.pe" is the only key with a dot that's not a number suffix
+		if key != "x.pe" {
+			key, _, _ = strings.Cut(key, ".")
+		}
+
+		if err := m.set(key, value); err != nil {
+			return nil, err
+		}
+	}
+
+	if !m.HasV1() && !m.HasV2() {
+		return nil, errors.New("Magnet link has no btih or btmh info hash")
+	}
+
+	return m, nil
+}
+
+func (m *Magnet) set(key, value string) error {
+	switch key {
+	case "xt":
+		return m.setExactTopic(value)
+	case "dn":
+		m.DisplayName = value
+	case "tr":
+		m.Trackers = append(m.Trackers, value)
+	case "ws":
+		m.WebSeeds = append(m.WebSeeds, value)
+	case "x.pe":
+		if _, _, err := net.SplitHostPort(value); err != nil {
+			return errors.New(fmt.Sprintf("Invalid peer address '%s': %s", value, err))
+		}
+		m.Peers = append(m.Peers, value)
+	case "so":
+		ranges, err := parseSelectOnly(value)
+		if err != nil {
+			return err
+		}
+		m.SelectOnly = append(m.SelectOnly, ranges...)
+	}
+
+	// anything else (xl, as, kt...) is not used
+	return nil
+}
+
+func (m *Magnet) setExactTopic(xt string) error {
+	switch {
+	case strings.HasPrefix(strings.ToLower(xt), btihPrefix):
+		hash, err := decodeBtih(xt[len(btihPrefix):])
+		if err != nil {
+			return err
+		}
+		m.InfoHash = hash
+
+	case strings.HasPrefix(strings.ToLower(xt), btmhPrefix):
+		multihash := strings.ToLower(xt[len(btmhPrefix):])
+		if !strings.HasPrefix(multihash, sha256Multihash) {
+			return errors.New(fmt.Sprintf("Unsupported btmh multihash '%s', expected a SHA-256 one", multihash))
+		}
+
+		b, err := hex.DecodeString(multihash[len(sha256Multihash):])
+		if err != nil || len(b) != 32 {
+			return errors.New(fmt.Sprintf("Invalid btmh info hash '%s'", multihash))
+		}
+		copy(m.InfoHashV2[:], b)
+	}
+
+	// other kinds of "xt" (ed2k, sha1...) are for other networks
+	return nil
+}
+
+// decodeBtih decodes a v1 info hash written either as 40 hex digits or as 32 base32 characters.
+func decodeBtih(s string) ([20]byte, error) {
+	var hash [20]byte
+
+	var b []byte
+	var err error
+	switch len(s) {
+	case 40:
+		b, err = hex.DecodeString(s)
+	case 32:
+		b, err = base32.StdEncoding.DecodeString(strings.ToUpper(s))
+	default:
+		return hash, errors.New(fmt.Sprintf("Expected btih to be 40 hex or 32 base32 characters got %d instead", len(s)))
+	}
+
+	if err != nil {
+		return hash, errors.New(fmt.Sprintf("Invalid btih info hash '%s': %s", s, err))
+	}
+
+	copy(hash[:], b)
+	return hash, nil
+}
+
+// parseSelectOnly parses a "so" value such as "0,2,4-6".
+func parseSelectOnly(so string) ([]FileRange, error) {
+	ranges := make([]FileRange, 0)
+	for _, part := range strings.Split(so, ",") {
+		first, last, isRange := strings.Cut(part, "-")
+		if !isRange {
+			last = first
+		}
+
+		f, err := strconv.Atoi(first)
+		if err != nil || f < 0 {
+			return nil, errors.New(fmt.Sprintf("Invalid file index '%s' in so", part))
+		}
+		l, err := strconv.Atoi(last)
+		if err != nil || l < f {
+			return nil, errors.New(fmt.Sprintf("Invalid file range '%s' in so", part))
+		}
+
+		ranges = append(ranges, FileRange{First: f, Last: l})
+	}
+	return ranges, nil
+}
+
+// String builds the magnet link, the info hashes come first followed by the other parameters
+// in the same order as the fields of Magnet.
+func (m Magnet) String() string {
+	params := make([]string, 0)
+	if m.HasV1() {
+		params = append(params, "xt="+btihPrefix+hex.EncodeToString(m.InfoHash[:]))
+	}
+	if m.HasV2() {
+		params = append(params, "xt="+btmhPrefix+sha256Multihash+hex.EncodeToString(m.InfoHashV2[:]))
+	}
+
+	if m.DisplayName != "" {
+		params = append(params, "dn="+url.QueryEscape(m.DisplayName))
+	}
+	for _, tr := range m.Trackers {
+		params = append(params, "tr="+url.QueryEscape(tr))
+	}
+	for _, ws := range m.WebSeeds {
+		params = append(params, "ws="+url.QueryEscape(ws))
+	}
+	for _, peer := range m.Peers {
+		params = append(params, "x.pe="+url.QueryEscape(peer))
+	}
+
+	if len(m.SelectOnly) > 0 {
+		ranges := make([]string, len(m.SelectOnly))
+		for i, r := range m.SelectOnly {
+			ranges[i] = strconv.Itoa(r.First)
+			if r.Last != r.First {
+				ranges[i] += "-" + strconv.Itoa(r.Last)
+			}
+		}
+		params = append(params, "so="+strings.Join(ranges, ","))
+	}
+
+	return "magnet:?" + strings.Join(params, "&")
+}
+
+// FromTorrentFile builds the magnet link of a torrent with its name, trackers and web seeds.
+func FromTorrentFile(t *decoder.TorrentFile) *Magnet {
+	m := &Magnet{DisplayName: t.Info.Name, WebSeeds: t.URLList}
+	if t.Info.HasV1() {
+		m.InfoHash = t.InfoHash()
+	}
+	if t.Info.HasV2() {
+		m.InfoHashV2 = t.InfoHashV2()
+	}
+
+	seen := make(map[string]bool)
+	addTracker := func(tr string) {
+		if tr != "" && !seen[tr] {
+			seen[tr] = true
+			m.Trackers = append(m.Trackers, tr)
+		}
+	}
+
+	addTracker(t.Announce)
+	for _, tier := range t.AnnounceList {
+		for _, tr := range tier {
+			addTracker(tr)
+		}
+	}
+
+	return m
+}
@@ -0,0 +1,221 @@
+package magnet
+
+import (
+	"encoding/hex"
+	"gotorrent/decoder"
+	"reflect"
+	"testing"
+)
+
+const (
+	testHash   = "c9e15763f722f23e98a29decdfae341b98d53056"
+	testHashV2 = "631a31dd0a46257d5078c0dee4e66e26f73e42ac47e2541d5ee5c9e2dd3d0b26"
+)
+
+func hashOf(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		uri      string
+		expected Magnet
+	}{
+		{
+			"magnet:?xt=urn:btih:" + testHash,
+			Magnet{},
+		},
+		{
+			// same hash in base32, lower case works too
+			"MAGNET:?xt=urn:btih:ZHQVOY7XELZD5GFCTXWN7LRUDOMNKMCW&dn=Some+Name",
+			Magnet{DisplayName: "Some Name"},
+		},
+		{
+			"magnet:?xt=urn:btih:zhqvoy7xelzd5gfctxwn7lrudomnkmcw&tr=http%3A%2F%2Ft1%2Fannounce&tr=udp%3A%2F%2Ft2%3A80&ws=http%3A%2F%2Fseed%2F",
+			Magnet{Trackers: []string{"http://t1/announce", "udp://t2:80"}, WebSeeds: []string{"http://seed/"}},
+		},
+		{
+			"magnet:?xt=urn:btih:" + testHash + "&x.pe=10.0.0.1:6881&x.pe=[::1]:51413&so=0,2,4-6",
+			Magnet{Peers: []string{"10.0.0.1:6881", "[::1]:51413"}, SelectOnly: []FileRange{{0, 0}, {2, 2}, {4, 6}}},
+		},
+		{
+			// numbered parameters & unknown ones
+			"magnet:?xt.1=urn:btih:" + testHash + "&tr.1=http://a&xl=1234&kt=ubuntu",
+			Magnet{Trackers: []string{"http://a"}},
+		},
+	}
+
+	for _, test := range tests {
+		copy(test.expected.InfoHash[:], hashOf(t, testHash))
+
+		res, err := Parse(test.uri)
+		if err != nil {
+			t.Errorf("expected no error for %s got %s instead", test.uri, err)
+			continue
+		}
+		if !reflect.DeepEqual(*res, test.expected) {
+			t.Errorf("expected %+v for %s got %+v instead", test.expected, test.uri, *res)
+		}
+	}
+}
+
+func TestParseOrder(t *testing.T) {
+	uri := "magnet:?xt=urn:btih:" + testHash + "&tr.1=http://a&ws=http://w1&tr.2=http://b&tr.3=http://c" +
+		"&ws=http://w2&tr=http://d&x.pe=10.0.0.2:1&x.pe=10.0.0.1:1"
+
+	first, err := Parse(uri)
+	if err != nil {
+		t.Fatalf("expected no error got %s instead", err)
+	}
+
+	// parameters are kept in the order of the link, maps would shuffle them
+	for range 20 {
+		res, _ := Parse(uri)
+		if !reflect.DeepEqual(res.Trackers, []string{"http://a", "http://b", "http://c", "http://d"}) ||
+			!reflect.DeepEqual(res.WebSeeds, []string{"http://w1", "http://w2"}) ||
+			!reflect.DeepEqual(res.Peers, []string{"10.0.0.2:1", "10.0.0.1:1"}) {
+			t.Fatalf("unexpected order %+v", res)
+		}
+		if res.String() != first.String() {
+			t.Fatalf("expected %s got %s instead", first, res)
+		}
+	}
+}
+
+func TestParseV2(t *testing.T) {
+	tests := []struct {
+		uri   string
+		hasV1 bool
+		hasV2 bool
+	}{
+		{"magnet:?xt=urn:btmh:1220" + testHashV2, false, true},
+		// hybrid
+		{"magnet:?xt=urn:btih:" + testHash + "&xt=urn:btmh:1220" + testHashV2 + "&dn=x", true, true},
+	}
+
+	for _, test := range tests {
+		res, err := Parse(test.uri)
+		if err != nil {
+			t.Errorf("expected no error for %s got %s instead", test.uri, err)
+			continue
+		}
+		if res.HasV1() != test.hasV1 || res.HasV2() != test.hasV2 {
+			t.Errorf("expected v1 %v & v2 %v for %s got %v & %v instead", test.hasV1, test.hasV2, test.uri, res.HasV1(), res.HasV2())
+		}
+		if res.HasV2() && hex.EncodeToString(res.InfoHashV2[:]) != testHashV2 {
+			t.Errorf("unexpected v2 hash %x", res.InfoHashV2)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"http://example.com/?xt=urn:btih:" + testHash,
+		"magnet:?dn=no+hash",
+		"magnet:?xt=urn:ed2k:31d6cfe0d16ae931b73c59d7e0c089c0",
+		"magnet:?xt=urn:btih:abc",
+		"magnet:?xt=urn:btih:" + testHash[:39] + "z",
+		"magnet:?xt=urn:btih:0000000000000000000000000000000!",
+		// sha1 multihash
+		"magnet:?xt=urn:btmh:1114" + testHash,
+		"magnet:?xt=urn:btmh:1220" + testHashV2[:62],
+		"magnet:?xt=urn:btih:" + testHash + "&x.pe=nope",
+		"magnet:?xt=urn:btih:" + testHash + "&so=1,a",
+		"magnet:?xt=urn:btih:" + testHash + "&so=5-2",
+		"magnet:?xt=urn:btih:" + testHash + "&so=-1",
+		"magnet:?xt=urn:btih:" + testHash + "&dn=%zz",
+	}
+
+	for _, test := range tests {
+		if _, err := Parse(test); err == nil {
+			t.Errorf("expected an error for %s", test)
+		}
+	}
+}
+
+func TestStringRoundTrip(t *testing.T) {
+	m := Magnet{
+		DisplayName: "a name & more",
+		Trackers:    []string{"http://t1/announce?x=1", "udp://t2:80"},
+		WebSeeds:    []string{"http://seed/"},
+		Peers:       []string{"10.0.0.1:6881"},
+		SelectOnly:  []FileRange{{0, 0}, {4, 6}},
+	}
+	copy(m.InfoHash[:], hashOf(t, testHash))
+	copy(m.InfoHashV2[:], hashOf(t, testHashV2))
+
+	expected := "magnet:?xt=urn:btih:" + testHash + "&xt=urn:btmh:1220" + testHashV2 +
+		"&dn=a+name+%26+more&tr=http%3A%2F%2Ft1%2Fannounce%3Fx%3D1&tr=udp%3A%2F%2Ft2%3A80" +
+		"&ws=http%3A%2F%2Fseed%2F&x.pe=10.0.0.1%3A6881&so=0,4-6"
+	if m.String() != expected {
+		t.Errorf("expected %s got %s instead", expected, m.String())
+	}
+
+	parsed, err := Parse(m.String())
+	if err != nil {
+		t.Fatalf("expected no error got %s instead", err)
+	}
+	if !reflect.DeepEqual(*parsed, m) {
+		t.Errorf("expected %+v got %+v instead", m, *parsed)
+	}
+}
+
+func TestSelects(t *testing.T) {
+	m := Magnet{SelectOnly: []FileRange{{0, 0}, {4, 6}}}
+	for idx, expected := range []bool{true, false, false, false, true, true, true, false} {
+		if m.Selects(idx) != expected {
+			t.Errorf("expected Selects(%d) to be %v", idx, expected)
+		}
+	}
+
+	if !(Magnet{}).Selects(42) {
+		t.Errorf("expected every file to be selected without so")
+	}
+}
+
+func TestFromTorrentFile(t *testing.T) {
+	torrent, err := decoder.DecodeTorrentFile("../decoder/files/test.torrent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	torrent.AnnounceList = [][]string{{torrent.Announce, "http://backup"}, {"udp://other:80"}}
+	torrent.URLList = []string{"http://seed/"}
+
+	m := FromTorrentFile(torrent)
+	expectedTrackers := []string{torrent.Announce, "http://backup", "udp://other:80"}
+	if m.InfoHash != torrent.InfoHash() || m.HasV2() || m.DisplayName != torrent.Info.Name ||
+		!reflect.DeepEqual(m.Trackers, expectedTrackers) || !reflect.DeepEqual(m.WebSeeds, []string{"http://seed/"}) {
+		t.Errorf("unexpected magnet %+v", m)
+	}
+
+	parsed, err := Parse(m.String())
+	if err != nil {
+		t.Fatalf("expected no error got %s instead", err)
+	}
+	if !reflect.DeepEqual(parsed, m) {
+		t.Errorf("expected %+v got %+v instead", m, parsed)
+	}
+}
+
+func TestFromTorrentFileV2(t *testing.T) {
+	torrent := &decoder.TorrentFile{
+		Info: decoder.TorrentInfo{
+			Name:        "v2",
+			PieceLength: decoder.BlockSize,
+			MetaVersion: 2,
+			FileTree: &decoder.FileTree{Children: map[string]*decoder.FileTree{
+				"v2": {File: &decoder.FileTreeEntry{Length: 0}},
+			}},
+		},
+	}
+
+	m := FromTorrentFile(torrent)
+	if m.HasV1() || m.InfoHashV2 != torrent.InfoHashV2() {
+		t.Errorf("expected only a v2 hash got %+v instead", m)
+	}
+}
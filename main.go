@@ -1,24 +1,124 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
-	"gotorrent/decoder"
+	"io"
+	"os"
+	"strings"
 )
 
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands = []command{
+	{"info", "show the name, hashes, files and trackers of a torrent", runInfo},
+	{"create", "create a torrent out of a file or directory", runCreate},
+	{"verify", "check downloaded data against a torrent's hashes", runVerify},
+	{"download", "download a torrent (not implemented yet)", runDownload},
+	{"seed", "seed a torrent (not implemented yet)", runSeed},
+	{"magnet", "print the magnet link of a torrent or what a magnet link holds", runMagnet},
+	{"tracker", "announce a torrent to its tracker and print the peers", runTracker},
+	{"bencode", "convert, query and diff bencoded files", runBencode},
+	{"lint", "check torrents for invalid or unsafe metadata", runLint},
+	{"edit", "change the trackers & metadata of a torrent without changing its info hash", runEdit},
+}
+
+// Exit codes, 2 is also what the flag package uses for bad flags.
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+// usageError is returned by commands called with the wrong arguments,
+// an empty message means the flag package already printed what was wrong.
+type usageError struct {
+	msg string
+}
+
+func (e usageError) Error() string {
+	return e.msg
+}
+
+// parseFlags parses the arguments of a command, any error other than -h is a usage error.
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return usageError{}
+	}
+	return nil
+}
+
 func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	if len(args) == 0 {
+		printUsage(os.Stderr)
+		return exitUsage
+	}
+
+	switch {
+	case args[0] == "help" || args[0] == "-h" || args[0] == "-help" || args[0] == "--help":
+		// "help <command>" shows the flags of the command
+		if len(args) > 1 {
+			return run([]string{args[1], "-h"})
+		}
+		printUsage(os.Stdout)
+		return exitOK
+
+	case strings.HasPrefix(args[0], "-decode") || strings.HasPrefix(args[0], "--decode"):
+		// this used to be the only thing the tool did, "info" shows all of it and more
+		fmt.Fprintln(os.Stderr, "-decode is deprecated, use 'gotorrent info <file.torrent>' instead")
+		if _, file, ok := strings.Cut(args[0], "="); ok {
+			args = []string{"info", file}
+		} else {
+			args = append([]string{"info"}, args[1:]...)
+		}
+	}
 
-	trntFile := flag.String("decode", "", "specify torrent file to decode")
-	flag.Parse()
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return exitCode(cmd.run(args[1:]))
+		}
+	}
 
-	if *trntFile != "" {
-		decoded, err := decoder.DecodeTorrentFile(*trntFile)
-		if err != nil {
-			fmt.Printf("Failed to decode %s due to [Error]: %s\n", *trntFile, err)
+	fmt.Fprintf(os.Stderr, "Unknown command '%s'\n\n", args[0])
+	printUsage(os.Stderr)
+	return exitUsage
+}
+
+func exitCode(err error) int {
+	var usageErr usageError
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.As(err, &usageErr):
+		if usageErr.msg != "" {
+			fmt.Fprintln(os.Stderr, usageErr.msg)
 		}
+		return exitUsage
+	default:
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+}
 
-		fmt.Println("---------- Decoded file ----------")
-		fmt.Printf("%+v\n", decoded)
-		fmt.Println("---------- End decoded file ----------")
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: gotorrent <command> [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-9s %s\n", cmd.name, cmd.summary)
 	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'gotorrent help <command>' to see the flags of a command.")
 }
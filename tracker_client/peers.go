@@ -0,0 +1,112 @@
+package trackerclient
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"gotorrent/bencode"
+	"net/netip"
+)
+
+// CompactPeers is the "peers" list of a tracker response.
+// It decodes both the compact form (BEP 23), a string made of 6 bytes per peer
+// (4 for the IPv4 + 2 for the port in network byte order), and the original list of dicts.
+// It always encodes into the compact form.
+type CompactPeers []UdpPeer
+
+// CompactPeers6 is the "peers6" list of a tracker response (BEP 7),
+// same as CompactPeers but with 18 bytes per peer (16 for the IPv6 + 2 for the port).
+type CompactPeers6 []UdpPeer
+
+func (p CompactPeers) MarshalBencode() ([]byte, error) {
+	return marshalCompactPeers(p, 4)
+}
+
+func (p *CompactPeers) UnmarshalBencode(data []byte) error {
+	peers, err := unmarshalCompactPeers(data, 4)
+	if err != nil {
+		return err
+	}
+
+	*p = peers
+	return nil
+}
+
+func (p CompactPeers6) MarshalBencode() ([]byte, error) {
+	return marshalCompactPeers(p, 16)
+}
+
+func (p *CompactPeers6) UnmarshalBencode(data []byte) error {
+	peers, err := unmarshalCompactPeers(data, 16)
+	if err != nil {
+		return err
+	}
+
+	*p = peers
+	return nil
+}
+
+func marshalCompactPeers(peers []UdpPeer, ipSize int) ([]byte, error) {
+	compact := make([]byte, 0, len(peers)*(ipSize+2))
+	for _, p := range peers {
+		ip := p.Ip.Unmap()
+		if ip.BitLen() != ipSize*8 {
+			return nil, fmt.Errorf("Cannot encode %s in a list of %d bytes addresses", p.Ip, ipSize)
+		}
+
+		compact = append(compact, ip.AsSlice()...)
+		compact = binary.BigEndian.AppendUint16(compact, p.Port)
+	}
+
+	return bencode.Marshal(compact)
+}
+
+type dictPeer struct {
+	PeerId string `bencode:"peer id,omitempty"`
+	Ip     string `bencode:"ip"`
+	Port   uint16 `bencode:"port"`
+}
+
+func unmarshalCompactPeers(data []byte, ipSize int) ([]UdpPeer, error) {
+	v, err := bencode.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	peers := make([]UdpPeer, 0)
+	switch v.Kind() {
+	case bencode.String:
+		compact := v.Bytes()
+		peerSize := ipSize + 2
+		if len(compact)%peerSize != 0 {
+			return nil, fmt.Errorf("Expected compact peers length to be a multiple of %d got %d instead", peerSize, len(compact))
+		}
+
+		for i := 0; i < len(compact); i += peerSize {
+			ip, _ := netip.AddrFromSlice(compact[i : i+ipSize])
+			peers = append(peers, UdpPeer{
+				Ip:   ip,
+				Port: binary.BigEndian.Uint16(compact[i+ipSize : i+peerSize]),
+			})
+		}
+
+	case bencode.List:
+		var dicts []dictPeer
+		if err := v.Decode(&dicts); err != nil {
+			return nil, err
+		}
+
+		for _, d := range dicts {
+			ip, err := netip.ParseAddr(d.Ip)
+			if err != nil {
+				return nil, fmt.Errorf("Cannot parse peer ip '%s': %s", d.Ip, err)
+			}
+			peers = append(peers, UdpPeer{Ip: ip, Port: d.Port})
+		}
+
+	default:
+		return nil, errors.New("Expected peers to either be a list of dicts or a byte string")
+	}
+
+	return peers, nil
+}
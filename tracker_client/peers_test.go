@@ -0,0 +1,106 @@
+package trackerclient
+
+import (
+	"gotorrent/bencode"
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func TestCompactPeers(t *testing.T) {
+	peers := CompactPeers{
+		{Ip: netip.MustParseAddr("10.0.0.1"), Port: 6881},
+		{Ip: netip.MustParseAddr("192.168.1.2"), Port: 80},
+	}
+
+	b, err := bencode.Marshal(peers)
+	if err != nil {
+		t.Fatalf("expected no error got %s instead", err)
+	}
+
+	expected := "12:\x0a\x00\x00\x01\x1a\xe1\xc0\xa8\x01\x02\x00\x50"
+	if string(b) != expected {
+		t.Errorf("expected %q got %q instead", expected, b)
+	}
+
+	var res CompactPeers
+	if err := bencode.Unmarshal(b, &res); err != nil {
+		t.Fatalf("expected no error got %s instead", err)
+	}
+	if !reflect.DeepEqual(res, peers) {
+		t.Errorf("expected %+v got %+v instead", peers, res)
+	}
+
+	// IPv6 peers cannot be put in a list of IPv4 peers
+	if _, err := bencode.Marshal(CompactPeers{{Ip: netip.MustParseAddr("::1"), Port: 1}}); err == nil {
+		t.Errorf("expected an error when encoding an IPv6 peer")
+	}
+}
+
+func TestCompactPeers6(t *testing.T) {
+	peers := CompactPeers6{{Ip: netip.MustParseAddr("2001:db8::1"), Port: 6881}}
+
+	b, err := bencode.Marshal(peers)
+	if err != nil {
+		t.Fatalf("expected no error got %s instead", err)
+	}
+
+	var res CompactPeers6
+	if err := bencode.Unmarshal(b, &res); err != nil {
+		t.Fatalf("expected no error got %s instead", err)
+	}
+	if !reflect.DeepEqual(res, peers) {
+		t.Errorf("expected %+v got %+v instead", peers, res)
+	}
+}
+
+func TestHTTPAnnounceResponse(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected httpAnnounceResponse
+	}{
+		{
+			input: "d8:completei3e10:incompletei1e8:intervali1800e5:peers6:\x0a\x00\x00\x01\x1a\xe1e",
+			expected: httpAnnounceResponse{
+				Interval:   1800,
+				Complete:   3,
+				Incomplete: 1,
+				Peers:      CompactPeers{{Ip: netip.MustParseAddr("10.0.0.1"), Port: 6881}},
+			},
+		},
+		{
+			input: "d8:intervali900e5:peersld2:ip8:10.0.0.17:peer id20:aaaaaaaaaaaaaaaaaaaa4:porti6881eeee",
+			expected: httpAnnounceResponse{
+				Interval: 900,
+				Peers:    CompactPeers{{Ip: netip.MustParseAddr("10.0.0.1"), Port: 6881}},
+			},
+		},
+		{
+			input:    "d14:failure reason7:go awaye",
+			expected: httpAnnounceResponse{FailureReason: "go away"},
+		},
+	}
+
+	for _, test := range tests {
+		var res httpAnnounceResponse
+		if err := bencode.Unmarshal([]byte(test.input), &res); err != nil {
+			t.Fatalf("expected no error for %q got %s instead", test.input, err)
+		}
+
+		if !reflect.DeepEqual(res, test.expected) {
+			t.Errorf("input = %q expected %+v got %+v instead", test.input, test.expected, res)
+		}
+	}
+
+	invalid := []string{
+		"d5:peers5:abcdee",
+		"d5:peersi1ee",
+		"d5:peersld2:ip9:not an ip4:porti1eeee",
+	}
+	for _, input := range invalid {
+		var res httpAnnounceResponse
+		if err := bencode.Unmarshal([]byte(input), &res); err == nil {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}
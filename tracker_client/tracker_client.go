@@ -7,12 +7,11 @@ package trackerclient
 import (
 	"bytes"
 	"context"
-	"crypto/sha1"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"gotorrent/bencode"
 	"gotorrent/decoder"
-	"gotorrent/encoder"
 	"gotorrent/utils"
 	"io"
 	"log"
@@ -71,23 +70,15 @@ type TrackerClient struct {
 }
 
 func NewTrackerClient(torrentFile decoder.TorrentFile) (*TrackerClient, error) {
-	info, err := encoder.Encode(torrentFile.Info)
-	if err != nil {
-		return nil, err
-	}
-
-	h := sha1.New()
-	if _, err := io.WriteString(h, info); err != nil {
-		return nil, err
-	}
-	infoHash := string(h.Sum(nil))
+	hash := torrentFile.InfoHash()
+	infoHash := string(hash[:])
 
 	u, err := url.Parse(torrentFile.Announce)
 	if err != nil {
 		return nil, err
 	}
 
-	if strings.HasPrefix(u.Scheme, "http") && strings.HasPrefix(u.Scheme, "udp") {
+	if !strings.HasPrefix(u.Scheme, "http") && !strings.HasPrefix(u.Scheme, "udp") {
 		return nil, fmt.Errorf("Unsupported protofol for %s, we only support HTTP/UDP", u.Scheme)
 	}
 
@@ -98,7 +89,7 @@ func NewTrackerClient(torrentFile decoder.TorrentFile) (*TrackerClient, error) {
 		numPeersWant: 5,
 
 		downloaded: 0,
-		left:       int64(torrentFile.Info.Length),
+		left:       torrentFile.Info.TotalLength(),
 		status:     none,
 
 		peerId: generateRandomPeerId(),
@@ -153,7 +144,26 @@ func (tc *TrackerClient) GetPeers() []UdpPeer {
 	return peers
 }
 
-type announceResponse struct {
+// httpAnnounceResponse is the bencoded body returned by HTTP trackers.
+type httpAnnounceResponse struct {
+	FailureReason string        `bencode:"failure reason,omitempty"`
+	Interval      int32         `bencode:"interval"`
+	Complete      int32         `bencode:"complete,omitempty"`
+	Incomplete    int32         `bencode:"incomplete,omitempty"`
+	Peers         CompactPeers  `bencode:"peers,omitempty"`
+	Peers6        CompactPeers6 `bencode:"peers6,omitempty"`
+}
+
+// Tracker responses come from the network so bound what we accept from them.
+var trackerResponseLimits = bencode.Limits{
+	MaxDepth:     4,
+	MaxStringLen: 1 << 20,
+	MaxAlloc:     4 << 20,
+	MaxElements:  10_000,
+}
+
+// AnnounceResponse is what trackers answer to announces, whether they're HTTP or UDP ones.
+type AnnounceResponse struct {
 	// The number of seconds you should wait until re-announcing yourself.
 	Interval int32
 
@@ -163,7 +173,23 @@ type announceResponse struct {
 	Peers []UdpPeer
 }
 
-func (tc *TrackerClient) announce() (*announceResponse, error) {
+// Announce sends a single announce request and keeps the peers it gets back (see GetPeers),
+// Start does the same every "interval" seconds.
+func (tc *TrackerClient) Announce() (*AnnounceResponse, error) {
+	resp, err := tc.announce()
+	if err != nil {
+		return nil, err
+	}
+
+	tc.mu.Lock()
+	tc.announceInterval = resp.Interval
+	tc.peers = resp.Peers
+	tc.mu.Unlock()
+
+	return resp, nil
+}
+
+func (tc *TrackerClient) announce() (*AnnounceResponse, error) {
 	if strings.Index(tc.announceUrl.Scheme, "http") == 0 {
 		return tc.sendHTTPAnnounceRequest()
 	}
@@ -172,7 +198,7 @@ func (tc *TrackerClient) announce() (*announceResponse, error) {
 
 // this does not yet work and it's badly tested
 // @TODO: test this
-func (tc *TrackerClient) sendHTTPAnnounceRequest() (*announceResponse, error) {
+func (tc *TrackerClient) sendHTTPAnnounceRequest() (*AnnounceResponse, error) {
 	u, err := tc.getHttpTrackerUrl()
 	if err != nil {
 		return nil, err
@@ -194,72 +220,26 @@ func (tc *TrackerClient) sendHTTPAnnounceRequest() (*announceResponse, error) {
 		return nil, fmt.Errorf("HTTP[%d] while calling tracker server %s\n %s", resp.StatusCode, u, string(b))
 	}
 
-	body, err := decoder.Decode(string(b))
-	if err != nil {
+	var body httpAnnounceResponse
+	dec := bencode.NewDecoder(bytes.NewReader(b))
+	dec.SetLimits(trackerResponseLimits)
+	if err := dec.Decode(&body); err != nil {
 		return nil, err
 	}
 
-	failureReason, _ := body["failure reason"].(string)
-	if failureReason != "" {
-		return nil, fmt.Errorf("%s", failureReason)
-	}
-
-	interval, _ := body["interval"].(int)
-	leechers, _ := body["leechers"].(int)
-
-	peers := make([]UdpPeer, 0)
-	anyPeers, _ := body["peers"].([]any)
-	for _, p := range anyPeers {
-		v, ok := p.(map[string]any)
-		if ok {
-			port, _ := v["port"].(int)
-
-			ip, _ := v["ip"].(int)
-			buff := new(bytes.Buffer)
-			if err := binary.Write(buff, binary.NativeEndian, ip); err != nil {
-				return nil, err
-			}
-
-			peers = append(peers, UdpPeer{
-				Ip:   netip.AddrFrom4([4]byte(buff.Bytes())),
-				Port: uint16(port),
-			})
-		} else {
-			// if it's not a dict then it should be a byte string
-			v, ok := p.(string)
-			if !ok {
-				return nil, errors.New("Expected peer to either be dict or byte arr")
-			}
-
-			r := bytes.NewBuffer([]byte(v))
-			var (
-				ip   [4]byte
-				port uint16
-			)
-
-			if err := binary.Read(r, binary.NativeEndian, &ip); err != nil {
-				return nil, err
-			}
-
-			if err := binary.Read(r, binary.NativeEndian, &port); err != nil {
-				return nil, err
-			}
-
-			peers = append(peers, UdpPeer{
-				Ip:   netip.AddrFrom4(ip),
-				Port: uint16(port),
-			})
-		}
+	if body.FailureReason != "" {
+		return nil, fmt.Errorf("%s", body.FailureReason)
 	}
 
-	return &announceResponse{
-		Interval: int32(interval),
-		Leechers: int32(leechers),
-		Peers:    peers,
+	return &AnnounceResponse{
+		Interval: body.Interval,
+		Leechers: body.Incomplete,
+		Seeders:  body.Complete,
+		Peers:    append(body.Peers, body.Peers6...),
 	}, nil
 }
 
-func (tc *TrackerClient) sendUDPAnnounceRequest() (*announceResponse, error) {
+func (tc *TrackerClient) sendUDPAnnounceRequest() (*AnnounceResponse, error) {
 	err := tc.setUpUDPConnectionId()
 	if err != nil {
 		return nil, err
@@ -336,7 +316,7 @@ func (tc *TrackerClient) sendUDPAnnounceRequest() (*announceResponse, error) {
 		return nil, fmt.Errorf("Received different transaction_id, sent %d and got %d", randomTransactionId, transactionId)
 	}
 
-	return &announceResponse{Interval: interval, Leechers: leechers, Seeders: seeders, Peers: peers}, nil
+	return &AnnounceResponse{Interval: interval, Leechers: leechers, Seeders: seeders, Peers: peers}, nil
 }
 
 func (tc *TrackerClient) writeAnnounceRequest(req *bytes.Buffer, transactionId int32) error {
@@ -349,7 +329,7 @@ func (tc *TrackerClient) writeAnnounceRequest(req *bytes.Buffer, transactionId i
 
 	var (
 		downloaded int64  = 0
-		left       int64  = int64(tc.torrentFile.Info.Length)
+		left       int64  = tc.torrentFile.Info.TotalLength()
 		uploaded   int64  = 0
 		ip         uint32 = 0
 		key        uint32 = rand.Uint32()
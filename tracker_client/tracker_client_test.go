@@ -20,7 +20,8 @@ func TestStartRequest(t *testing.T) {
 	}
 
 	d, _ := time.ParseDuration("1m")
-	ctx, _ := context.WithTimeout(context.Background(), d)
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
 
 	ch := make(chan error)
 	go client.Start(ctx, ch)
@@ -33,3 +34,23 @@ func TestStartRequest(t *testing.T) {
 		fmt.Printf("got error %s\n", v)
 	}
 }
+
+func TestNewTrackerClientSchemes(t *testing.T) {
+	tests := []struct {
+		announce string
+		valid    bool
+	}{
+		{"http://tracker/announce", true},
+		{"https://tracker/announce", true},
+		{"udp://tracker:80", true},
+		{"wss://tracker", false},
+		{"ftp://tracker", false},
+	}
+
+	for _, test := range tests {
+		_, err := NewTrackerClient(decoder.TorrentFile{Announce: test.announce})
+		if (err == nil) != test.valid {
+			t.Errorf("expected valid to be %v for %s got error %v instead", test.valid, test.announce, err)
+		}
+	}
+}
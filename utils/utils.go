@@ -3,6 +3,7 @@ package utils
 import (
 	"errors"
 	"fmt"
+	"gotorrent/bencode"
 	"net"
 	"net/url"
 	"reflect"
@@ -25,7 +26,15 @@ func StructToMap(s any) (map[string]any, error) {
 			continue
 		}
 
-		if field.Type.Kind() == reflect.Struct {
+		// types with a custom wire form are kept as their raw encoding
+		if marshaler, ok := val.Field(i).Interface().(bencode.BencodeMarshaler); ok {
+			b, err := marshaler.MarshalBencode()
+			if err != nil {
+				return nil, err
+			}
+
+			m[field.Name] = bencode.RawMessage(b)
+		} else if field.Type.Kind() == reflect.Struct {
 			nestedMap, err := StructToMap(val.Field(i).Interface())
 			if err != nil {
 				return nil, err
@@ -65,6 +74,20 @@ func MapToStruct(m map[string]any, s any) error {
 			continue
 		}
 
+		fieldValue := structValue.FieldByName(field.Name)
+		if u, ok := fieldValue.Addr().Interface().(bencode.BencodeUnmarshaler); ok {
+			// the map only holds the decoded value so encode it back for the unmarshaler
+			b, err := bencode.Marshal(v)
+			if err != nil {
+				return err
+			}
+
+			if err := u.UnmarshalBencode(b); err != nil {
+				return err
+			}
+			continue
+		}
+
 		nestedMap, ok := v.(map[string]any)
 		if ok {
 			err := MapToStruct(nestedMap, structValue.FieldByName(field.Name).Addr().Interface())
@@ -97,7 +120,12 @@ func MapToStruct(m map[string]any, s any) error {
 
 				structValue.FieldByName(field.Name).Set(reflect.ValueOf(res))
 			} else {
-				structValue.FieldByName(field.Name).Set(reflect.ValueOf(v))
+				// bencode ints are decoded as int64 so they need converting to the field's int type
+				rv := reflect.ValueOf(v)
+				if rv.Kind() != field.Type.Kind() && rv.CanConvert(field.Type) {
+					rv = rv.Convert(field.Type)
+				}
+				structValue.FieldByName(field.Name).Set(rv)
 			}
 		}
 	}
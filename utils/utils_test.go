@@ -1,7 +1,9 @@
 package utils
 
 import (
+	"gotorrent/bencode"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -95,3 +97,41 @@ func TestTransformName(t *testing.T) {
 		}
 	}
 }
+
+type csv string
+
+func (c csv) MarshalBencode() ([]byte, error) {
+	return bencode.Marshal(strings.Split(string(c), ","))
+}
+
+func (c *csv) UnmarshalBencode(data []byte) error {
+	var parts []string
+	if err := bencode.Unmarshal(data, &parts); err != nil {
+		return err
+	}
+
+	*c = csv(strings.Join(parts, ","))
+	return nil
+}
+
+func TestBencodeMarshalers(t *testing.T) {
+	type withCsv struct{ C csv }
+
+	m, err := StructToMap(withCsv{C: "a,b"})
+	if err != nil {
+		t.Fatalf("expected no error got %s instead", err)
+	}
+
+	expected := map[string]any{"C": bencode.RawMessage("l1:a1:be")}
+	if !reflect.DeepEqual(m, expected) {
+		t.Errorf("expected %+v got %+v instead", expected, m)
+	}
+
+	var res withCsv
+	if err := MapToStruct(map[string]any{"c": []any{"x", "y"}}, &res); err != nil {
+		t.Fatalf("expected no error got %s instead", err)
+	}
+	if res.C != "x,y" {
+		t.Errorf("expected x,y got %s instead", res.C)
+	}
+}
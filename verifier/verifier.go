@@ -0,0 +1,250 @@
+package verifier
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"gotorrent/decoder"
+	"io"
+	"io/fs"
+	"os"
+	"runtime"
+	"sync"
+)
+
+// FileResult is the state of one of the torrent's files on disk.
+type FileResult struct {
+	// Path is where the file is expected on disk
+	Path    string
+	Length  int64
+	Missing bool
+	// Complete is set when the file has the expected size and all of its data is valid
+	Complete bool
+}
+
+type Result struct {
+	// Pieces tells which v1 pieces match their hash, it's empty for v2 only torrents
+	Pieces []bool
+	// Files are in the torrent's order, padding files are left out
+	Files []FileResult
+}
+
+// Complete reports whether all the data of the torrent is on disk and valid.
+func (r Result) Complete() bool {
+	for _, f := range r.Files {
+		if !f.Complete {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidPieces returns how many pieces match their hash.
+func (r Result) ValidPieces() int {
+	n := 0
+	for _, ok := range r.Pieces {
+		if ok {
+			n++
+		}
+	}
+	return n
+}
+
+// Verify checks the data of t saved under root (see decoder.TorrentInfo.DiskPath) against the torrent's hashes,
+// v1 pieces are hashed by "workers" goroutines (the number of CPUs when 0) and v2 only torrents
+// are checked file by file against their pieces root.
+// Missing or truncated files are reported in the result, the error is for anything else
+// (unsafe paths, read errors...).
+func Verify(t *decoder.TorrentFile, root string, workers int) (*Result, error) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if t.Info.PieceLength <= 0 || t.Info.PieceLength > decoder.MaxPieceLength {
+		return nil, errors.New(fmt.Sprintf("Expected a piece length between 1 and %d got %d instead", decoder.MaxPieceLength, t.Info.PieceLength))
+	}
+
+	if t.Info.HasV1() {
+		return verifyV1(t.Info, root, workers)
+	}
+	return verifyV2(t.Info, root)
+}
+
+// chunkSize is how much of a piece is read at once, pieces can be up to decoder.MaxPieceLength
+// so they are hashed as they are read instead of being kept whole in memory.
+const chunkSize = 64 * 1024
+
+// diskFile is one of FileEntries with where it's on disk.
+type diskFile struct {
+	entry decoder.FileEntry
+	path  string
+	size  int64
+	found bool
+}
+
+func verifyV1(info decoder.TorrentInfo, root string, workers int) (*Result, error) {
+	entries := info.FileEntries()
+	files := make([]diskFile, len(entries))
+	for i, f := range entries {
+		files[i].entry = f
+		if f.IsPadding() {
+			continue
+		}
+
+		path, err := info.DiskPath(root, f)
+		if err != nil {
+			return nil, err
+		}
+		files[i].path = path
+
+		stat, err := os.Stat(path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		if err == nil {
+			files[i].size = stat.Size()
+			files[i].found = stat.Mode().IsRegular()
+		}
+	}
+
+	pieces, err := checkPieces(info, files, workers)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Result{Pieces: pieces, Files: make([]FileResult, 0, len(files))}
+	for i, f := range files {
+		if f.entry.IsPadding() {
+			continue
+		}
+
+		complete := f.found && f.size == f.entry.Length
+		first, end := info.FilePieces(i)
+		for idx := first; idx < end && complete; idx++ {
+			complete = pieces[idx]
+		}
+
+		res.Files = append(res.Files, FileResult{Path: f.path, Length: f.entry.Length, Missing: !f.found, Complete: complete})
+	}
+	return res, nil
+}
+
+func checkPieces(info decoder.TorrentInfo, files []diskFile, workers int) ([]bool, error) {
+	pieces := make([]bool, info.NumPieces())
+	jobs := make(chan int)
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs error
+	)
+
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			buf := make([]byte, chunkSize)
+			for idx := range jobs {
+				ok, err := checkPiece(info, files, idx, buf)
+				if err != nil {
+					mu.Lock()
+					errs = errors.Join(errs, err)
+					mu.Unlock()
+					continue
+				}
+				pieces[idx] = ok
+			}
+		}()
+	}
+
+	for idx := range pieces {
+		jobs <- idx
+	}
+	close(jobs)
+	wg.Wait()
+
+	if errs != nil {
+		return nil, errs
+	}
+	return pieces, nil
+}
+
+// checkPiece reads the i-th piece from the files it spans, a piece with data in a missing
+// or too small file is not valid. buf is only used to read the piece chunk by chunk.
+func checkPiece(info decoder.TorrentInfo, files []diskFile, idx int, buf []byte) (bool, error) {
+	h := sha1.New()
+	for _, seg := range info.PieceSegments(idx) {
+		f := files[seg.File]
+		if f.entry.IsPadding() {
+			clear(buf)
+			for left := seg.Length; left > 0; {
+				n := min(left, int64(len(buf)))
+				h.Write(buf[:n])
+				left -= n
+			}
+			continue
+		}
+
+		if !f.found || f.size < seg.Offset+seg.Length {
+			return false, nil
+		}
+
+		if err := hashRange(h, f.path, buf, seg.Offset, seg.Length); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				// the file got smaller since it was checked
+				return false, nil
+			}
+			return false, err
+		}
+	}
+
+	hash := info.PieceHash(idx)
+	return bytes.Equal(h.Sum(nil), hash[:]), nil
+}
+
+// hashRange writes length bytes of the file at path starting at offset to w, len(buf) at a time.
+func hashRange(w io.Writer, path string, buf []byte, offset, length int64) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	n, err := io.CopyBuffer(w, io.NewSectionReader(f, offset, length), buf)
+	if err == nil && n < length {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func verifyV2(info decoder.TorrentInfo, root string) (*Result, error) {
+	res := &Result{Pieces: []bool{}, Files: make([]FileResult, 0)}
+	for _, f := range info.FilesV2() {
+		path, err := info.DiskPathV2(root, f)
+		if err != nil {
+			return nil, err
+		}
+
+		file, err := os.Open(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			res.Files = append(res.Files, FileResult{Path: path, Length: f.Length, Missing: true})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		hash, err := decoder.HashFileV2(file, info.PieceLength)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		complete := hash.Length == f.Length
+		if complete && f.Length > 0 {
+			complete = bytes.Equal(hash.PiecesRoot[:], []byte(f.PiecesRoot))
+		}
+		res.Files = append(res.Files, FileResult{Path: path, Length: f.Length, Complete: complete})
+	}
+	return res, nil
+}
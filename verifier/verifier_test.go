@@ -0,0 +1,167 @@
+package verifier
+
+import (
+	"gotorrent/creator"
+	"gotorrent/decoder"
+	"gotorrent/internal/testutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeTorrentData creates a "data" directory with a few files under a temp dir
+// and returns the temp dir.
+func writeTorrentData(t *testing.T) string {
+	return testutil.WriteFiles(t, map[string][]byte{
+		"data/a.bin":     testutil.Content(40000, 1),
+		"data/sub/b.bin": testutil.Content(30000, 2),
+		"data/sub/c.txt": testutil.Content(10, 3),
+	})
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name             string
+		hybrid           bool
+		modify           func(root string)
+		expectedComplete []bool
+		// expectedPieces is -1 when all the pieces are expected to be valid
+		expectedPieces int
+	}{
+		{"complete", false, func(root string) {}, []bool{true, true, true}, -1},
+		{"complete hybrid", true, func(root string) {}, []bool{true, true, true}, -1},
+		// b.bin shares its last piece with c.txt
+		{"missing file", false, func(root string) {
+			os.Remove(filepath.Join(root, "data", "sub", "c.txt"))
+		}, []bool{true, false, false}, 4},
+		{"corrupted", false, func(root string) {
+			f, _ := os.OpenFile(filepath.Join(root, "data", "a.bin"), os.O_WRONLY, 0)
+			f.WriteAt([]byte{0xff}, 20000)
+			f.Close()
+		}, []bool{false, true, true}, 4},
+		{"truncated", true, func(root string) {
+			os.Truncate(filepath.Join(root, "data", "sub", "b.bin"), 100)
+		}, []bool{true, false, true}, 4},
+	}
+
+	for _, test := range tests {
+		root := writeTorrentData(t)
+		torrent, err := creator.Create(filepath.Join(root, "data"), creator.Options{PieceLength: 16384, Hybrid: test.hybrid})
+		if err != nil {
+			t.Fatal(err)
+		}
+		test.modify(root)
+
+		res, err := Verify(torrent, root, 2)
+		if err != nil {
+			t.Fatalf("%s: expected no error got %s instead", test.name, err)
+		}
+
+		complete := make([]bool, len(res.Files))
+		for i, f := range res.Files {
+			complete[i] = f.Complete
+		}
+		if !reflect.DeepEqual(complete, test.expectedComplete) {
+			t.Errorf("%s: expected files complete to be %v got %v instead", test.name, test.expectedComplete, complete)
+		}
+
+		expectedPieces := test.expectedPieces
+		if expectedPieces == -1 {
+			expectedPieces = len(res.Pieces)
+		}
+		if res.ValidPieces() != expectedPieces {
+			t.Errorf("%s: expected %d valid pieces got %d instead", test.name, expectedPieces, res.ValidPieces())
+		}
+		if res.Complete() != (test.expectedPieces == -1) {
+			t.Errorf("%s: unexpected Complete() %v", test.name, res.Complete())
+		}
+	}
+}
+
+func TestVerifyV2(t *testing.T) {
+	root := writeTorrentData(t)
+	torrent, err := creator.Create(filepath.Join(root, "data"), creator.Options{PieceLength: 16384, Hybrid: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// drop the v1 metadata to only have the v2 one
+	torrent.Info.Pieces = nil
+	torrent.Info.Files = nil
+
+	res, err := Verify(torrent, root, 0)
+	if err != nil || !res.Complete() || len(res.Files) != 3 {
+		t.Fatalf("expected the data to be complete got %+v (%v) instead", res, err)
+	}
+	if res.Files[1].Path != filepath.Join(root, "data", "sub", "b.bin") {
+		t.Errorf("unexpected path %s", res.Files[1].Path)
+	}
+
+	os.WriteFile(filepath.Join(root, "data", "sub", "b.bin"), testutil.Content(30000, 9), 0644)
+	os.Remove(filepath.Join(root, "data", "a.bin"))
+	res, err = Verify(torrent, root, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Files[0].Missing || res.Files[1].Complete || !res.Files[2].Complete || res.Complete() {
+		t.Errorf("unexpected result %+v", res)
+	}
+}
+
+// pieces and files bigger than a chunk are hashed as they are read
+func TestVerifyLargePieces(t *testing.T) {
+	for _, hybrid := range []bool{false, true} {
+		root := writeTorrentData(t)
+		big := filepath.Join(root, "data", "big.bin")
+		os.WriteFile(big, testutil.Content(3*chunkSize+5, 4), 0644)
+		torrent, err := creator.Create(filepath.Join(root, "data"), creator.Options{PieceLength: 4 * chunkSize, Hybrid: hybrid})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := Verify(torrent, root, 0)
+		if err != nil || !res.Complete() {
+			t.Fatalf("hybrid %v: expected the data to be complete got %+v (%v) instead", hybrid, res, err)
+		}
+
+		f, _ := os.OpenFile(big, os.O_WRONLY, 0)
+		f.WriteAt([]byte{0xff}, 3*chunkSize+4)
+		f.Close()
+		res, err = Verify(torrent, root, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Files[1].Complete || !res.Files[3].Complete || res.Complete() {
+			t.Errorf("hybrid %v: unexpected result %+v", hybrid, res)
+		}
+	}
+}
+
+// the piece length used to be allocated as is for every worker
+func TestVerifyHugePieceLength(t *testing.T) {
+	input := "d4:infod6:lengthi1e4:name1:x12:piece lengthi99999999999999e6:pieces20:" + strings.Repeat("a", 20) + "ee"
+	torrent, err := decoder.ParseUnvalidated([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "x"), []byte("x"), 0644)
+	if _, err := Verify(torrent, root, 0); err == nil {
+		t.Errorf("expected an error for a piece length of %d", torrent.Info.PieceLength)
+	}
+}
+
+func TestVerifyUnsafePath(t *testing.T) {
+	root := writeTorrentData(t)
+	torrent, err := creator.Create(filepath.Join(root, "data"), creator.Options{PieceLength: 16384})
+	if err != nil {
+		t.Fatal(err)
+	}
+	torrent.Info.Files[0].Path = []string{"..", "a.bin"}
+
+	if _, err := Verify(torrent, root, 0); err == nil {
+		t.Errorf("expected an error for a path outside of the root")
+	}
+}